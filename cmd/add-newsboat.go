@@ -14,17 +14,17 @@ var addNewsboatCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		// Load bookmarks from file
 		bmks := ReadBookmarksFromFile()
+		aliases := LoadTagAliases()
 
-		// Create new bookmark entry
-		bm := bmks.NewEntry()
-
-		// Set URL
-		err := bm.Url.Parse(args[0])
+		// Add the same way as "voile add", with the details from Newsboat
+		nb := NewBookmark{
+			Url:         args[0],
+			Name:        args[1],
+			Description: args[2],
+		}
+		bm, err := AddBookmark(&bmks, nb, &FetchedPage{}, aliases)
 		CheckError(err)
 
-		bm.Name = args[1]
-		bm.Description = args[2]
-
 		// Edit in editor if requested
 		editFlag, _ := cmd.Flags().GetBool(EditFlagName)
 		if editFlag {
@@ -32,7 +32,7 @@ var addNewsboatCmd = &cobra.Command{
 			err = bmks.Verify()
 			CheckError(err)
 
-			EditBookmarkInEditor(&bmks, bm)
+			EditBookmarkInEditor(&bmks, bm, aliases)
 		}
 
 		// Save bookmarks back to file
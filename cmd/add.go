@@ -7,6 +7,7 @@ import (
 	"github.com/atotto/clipboard"
 	"github.com/spf13/cobra"
 
+	"github.com/DanNixon/voile/db"
 	"github.com/DanNixon/voile/web"
 )
 
@@ -30,37 +31,27 @@ var addCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Load bookmarks from file
-		bmks := ReadBookmarksFromFile()
-
 		// Create new bookmark entry
-		bm := bmks.NewEntry()
-
-		// Set URL
-		err := bm.Url.Parse(url)
+		nb := NewBookmark{Url: url}
+		nb.Name, _ = cmd.Flags().GetString(NameFlagName)
+		nb.Description, _ = cmd.Flags().GetString(DescFlagName)
+		nb.Tags, _ = cmd.Flags().GetStringSlice(TagsFlagName)
+		nb.FetchTitle, _ = cmd.Flags().GetBool(TitleNameFlagName)
+		nb.FetchMetadata, _ = cmd.Flags().GetBool(MetadataFlagName)
+		nb.Archive, _ = cmd.Flags().GetBool(ArchiveFlagName)
+
+		// Fetch the page without holding the lock
+		page, err := FetchNewBookmark(nb)
 		CheckError(err)
 
-		// Set name
-		titleNameFlag, _ := cmd.Flags().GetBool(TitleNameFlagName)
-		if titleNameFlag {
-			bm.Name, _ = web.FindTitleElement(bm.Url.Url)
-		} else {
-			if cmd.Flags().Changed(NameFlagName) {
-				bm.Name, _ = cmd.Flags().GetString(NameFlagName)
-			}
-		}
-
-		// Set description
-		if cmd.Flags().Changed(DescFlagName) {
-			bm.Description, _ = cmd.Flags().GetString(DescFlagName)
-		}
+		// Load bookmarks from file
+		bmks := ReadBookmarksFromFile()
+		aliases := LoadTagAliases()
 
-		// Set tags
-		if cmd.Flags().Changed(TagsFlagName) {
-			tags, _ := cmd.Flags().GetStringSlice(TagsFlagName)
-			for _, t := range tags {
-				bm.Tags.Append(t)
-			}
+		bm, err := AddBookmark(&bmks, nb, &page, aliases)
+		CheckError(err)
+		for _, w := range page.Warnings {
+			fmt.Println(w)
 		}
 
 		// Edit in editor if requested
@@ -70,7 +61,7 @@ var addCmd = &cobra.Command{
 			err = bmks.Verify()
 			CheckError(err)
 
-			EditBookmarkInEditor(&bmks, bm)
+			EditBookmarkInEditor(&bmks, bm, aliases)
 		}
 
 		// Save bookmarks back to file
@@ -81,12 +72,136 @@ var addCmd = &cobra.Command{
 	},
 }
 
+// Fields of a bookmark to add, empty fields are filled from the page if it is
+// fetched
+type NewBookmark struct {
+	Url         string
+	Name        string
+	Description string
+	Tags        []string
+
+	FetchTitle    bool
+	FetchMetadata bool
+	Archive       bool
+}
+
+// Details of the page of a new bookmark, fetched before the library is locked
+// as it can take as long as the HTTP timeout
+type FetchedPage struct {
+	Metadata web.PageMetadata
+	// Nil unless archiving
+	Snapshot *db.Snapshot
+	// Failures to get page details, which do not stop the bookmark being
+	// added
+	Warnings []error
+}
+
+// Fetches the page of a new bookmark for the details requested, only failing
+// to archive it is an error
+func FetchNewBookmark(nb NewBookmark) (FetchedPage, error) {
+	var page FetchedPage
+
+	// Check URL before fetching it
+	var u db.Url
+	err := u.Parse(nb.Url)
+	if err != nil {
+		return page, err
+	}
+
+	// Fetch page details
+	if nb.FetchTitle || nb.FetchMetadata {
+		page.Metadata, err = web.FetchMetadata(u.Url)
+		if err != nil {
+			page.Warnings = append(page.Warnings, err)
+		}
+	}
+
+	// Save a snapshot of the page
+	if nb.Archive {
+		snapshot, err := SnapshotBookmark(&db.Bookmark{Url: u})
+		if err != nil {
+			return page, err
+		}
+		page.Snapshot = &snapshot
+	}
+
+	return page, nil
+}
+
+// Adds a bookmark to the library with the details fetched for it, failing to
+// use the canonical URL is added to the page's warnings
+func AddBookmark(bmks *db.BookmarkLibrary, nb NewBookmark, page *FetchedPage, aliases db.TagAliases) (*db.Bookmark, error) {
+	// Check URL before creating an entry
+	var u db.Url
+	err := u.Parse(nb.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create new bookmark entry
+	bm := bmks.NewEntry()
+	bm.Url = u
+	md := page.Metadata
+
+	// Set name, only preferring the OpenGraph title when using metadata
+	if len(nb.Name) > 0 {
+		bm.Name = nb.Name
+	} else if nb.FetchMetadata && len(md.Title) > 0 {
+		bm.Name = md.Title
+	} else if len(md.PageTitle) > 0 {
+		bm.Name = md.PageTitle
+	}
+
+	// Set description
+	if len(nb.Description) > 0 {
+		bm.Description = nb.Description
+	} else if nb.FetchMetadata {
+		bm.Description = md.Description
+	}
+
+	if nb.FetchMetadata {
+		// Use the canonical URL, keeping the given URL as an alias
+		if len(md.CanonicalUrl) > 0 {
+			err = bmks.MoveUrl(bm.Number, md.CanonicalUrl)
+			if err != nil {
+				page.Warnings = append(page.Warnings, err)
+			}
+		}
+
+		if len(md.Author) > 0 || !md.Published.IsZero() || len(md.Favicon) > 0 {
+			bm.Metadata = &db.Metadata{
+				Author:  md.Author,
+				Favicon: md.Favicon,
+			}
+			if !md.Published.IsZero() {
+				bm.Metadata.Published = &md.Published
+			}
+		}
+	}
+
+	// Set tags
+	if len(nb.Tags) > 0 {
+		for _, t := range nb.Tags {
+			bm.Tags.Append(t)
+		}
+		aliases.Apply(&bm.Tags)
+	}
+
+	if page.Snapshot != nil {
+		bm.AddSnapshot(*page.Snapshot)
+	}
+
+	return bm, nil
+}
+
 func init() {
 	rootCmd.AddCommand(addCmd)
 
 	addCmd.Flags().BoolP(CopyFlagName, CopyFlagShort, false, "Copy URL from clipboard")
 	addCmd.Flags().BoolP(EditFlagName, EditFlagShort, false, "Add/edit the new bookmark in a text editor")
 	addCmd.Flags().BoolP(TitleNameFlagName, TitleNameFlagShort, false, "Get bookmark name from title of page")
+	addCmd.Flags().BoolP(MetadataFlagName, MetadataFlagShort, false, "Get name, description and canonical URL from page metadata")
+	addCmd.Flags().Bool(ArchiveFlagName, false, "Save a snapshot of the page")
 
 	addCmd.Flags().StringSliceP(TagsFlagName, TagsFlagShort, []string{}, "Tags")
 	addCmd.Flags().StringP(NameFlagName, NameFlagShort, "", "Name")
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/web"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive N",
+	Short: "Save a snapshot of a bookmarked page",
+	Long: `Saves a self contained copy of the page bookmarked with unique number N to the archive
+directory next to the bookmarks file, so it can be opened with "voile open --archived N" even
+if the page changes or disappears.`,
+	Args: IsValidBookmarkNumberArgument,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get bookmark number
+		bookmarkNumber, _ := strconv.Atoi(args[0])
+
+		// Fetch the page without holding the lock
+		current := ReadBookmarksFromFileReadOnly()
+		bm, err := current.GetByNumber(bookmarkNumber)
+		CheckError(err)
+		snapshot, err := SnapshotBookmark(bm)
+		CheckError(err)
+
+		// Record the snapshot
+		bmks := ReadBookmarksFromFile()
+		bm, err = bmks.GetByNumber(bookmarkNumber)
+		CheckError(err)
+		bm.AddSnapshot(snapshot)
+
+		SaveBookmarksToFile(&bmks)
+
+		fmt.Println(FormatBookmark(bm, 0))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(archiveCmd)
+}
+
+// The archive is kept next to the library
+func GetArchive() (db.Archive, error) {
+	fs, ok := Store.(db.FileStore)
+	if !ok {
+		return db.Archive{}, errors.New("Archiving requires a library stored in a file")
+	}
+
+	return db.ArchiveForFile(fs.Path()), nil
+}
+
+// Saves a snapshot of the bookmarked page to the archive
+func SnapshotBookmark(bm *db.Bookmark) (db.Snapshot, error) {
+	archive, err := GetArchive()
+	if err != nil {
+		return db.Snapshot{}, err
+	}
+
+	page, err := web.ArchivePage(bm.Url.Url)
+	if err != nil {
+		return db.Snapshot{}, err
+	}
+
+	file, err := archive.Put(page, ".html")
+	if err != nil {
+		return db.Snapshot{}, err
+	}
+
+	return db.Snapshot{
+		Taken: time.Now(),
+		File:  file,
+	}, nil
+}
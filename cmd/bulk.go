@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/tui"
+)
+
+var bulkCmd = &cobra.Command{
+	Use:   "bulk [QUERY]",
+	Short: "Change several bookmarks at once",
+	Long: `Changes every bookmark selected by the same flags and query as the root command, e.g.:
+  voile bulk --add-tags reading 'tag:toread added:<2020-01-01'
+  voile bulk --delete url:*.example.com
+  voile bulk --edit --tags news`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get action flags
+		addTags, _ := cmd.Flags().GetStringSlice(AddTagsFlagName)
+		removeTags, _ := cmd.Flags().GetStringSlice(RemoveTagsFlagName)
+		deleteFlag, _ := cmd.Flags().GetBool(DeleteFlagName)
+		editFlag, _ := cmd.Flags().GetBool(EditFlagName)
+		forceFlag, _ := cmd.Flags().GetBool(ForceFlagName)
+
+		if len(addTags) == 0 && len(removeTags) == 0 && !deleteFlag && !editFlag {
+			CheckError(errors.New("Nothing to do, give tags to add or remove, --delete or --edit"))
+		}
+		if deleteFlag && (editFlag || len(addTags) > 0 || len(removeTags) > 0) {
+			CheckError(errors.New("Bookmarks cannot be deleted and changed at the same time"))
+		}
+
+		p := FilterParamsFromFlags(cmd, args)
+		if p.IsEmpty() {
+			CheckError(errors.New("Refusing to change every bookmark, give a query or filter flags"))
+		}
+
+		// Load and filter bookmarks
+		bmks := ReadBookmarksFromFile()
+		results, err := FilterBookmarks(&p, &bmks)
+		CheckError(err)
+
+		if len(results) == 0 {
+			fmt.Println("No bookmarks selected.")
+			return
+		}
+
+		if deleteFlag {
+			for i, bm := range results {
+				fmt.Println(FormatBookmark(bm, i))
+			}
+
+			rm := forceFlag
+			if !rm {
+				rm, _ = tui.Confirm(fmt.Sprintf("Really remove %d bookmark(s)?", len(results)))
+			}
+			if !rm {
+				fmt.Println("Bookmarks not removed.")
+				return
+			}
+
+			// Deleting moves bookmarks, so collect numbers first
+			var numbers []int
+			for _, bm := range results {
+				numbers = append(numbers, bm.Number)
+			}
+			for _, number := range numbers {
+				err = bmks.DeleteByNumber(number)
+				CheckError(err)
+			}
+
+			SaveBookmarksToFile(&bmks)
+			fmt.Printf("Removed %d bookmark(s).\n", len(numbers))
+			return
+		}
+
+		aliases := LoadTagAliases()
+
+		// Change tags
+		if len(addTags) > 0 || len(removeTags) > 0 {
+			for _, bm := range results {
+				if retagBookmark(bm, addTags, removeTags) {
+					aliases.Apply(&bm.Tags)
+					bm.MarkUpdated()
+				}
+			}
+		}
+
+		// Edit all in one go, which may add or remove bookmarks
+		if editFlag {
+			results = bookmarksByNumber(&bmks, EditBookmarksInEditor(&bmks, results, aliases))
+		}
+
+		// Save bookmarks back to file
+		SaveBookmarksToFile(&bmks)
+
+		for i, bm := range results {
+			fmt.Println(FormatBookmark(bm, i))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bulkCmd)
+
+	AddFilteringFlags(bulkCmd)
+
+	bulkCmd.Flags().StringSlice(AddTagsFlagName, []string{}, "Tags to add")
+	bulkCmd.Flags().StringSlice(RemoveTagsFlagName, []string{}, "Tags to remove")
+	bulkCmd.Flags().Bool(DeleteFlagName, false, "Remove the bookmarks")
+	bulkCmd.Flags().BoolP(EditFlagName, EditFlagShort, false, "Edit the bookmarks in a text editor")
+	bulkCmd.Flags().Bool(ForceFlagName, false, "Remove without confirmation")
+}
+
+// Returns true if the tags changed
+func retagBookmark(bm *db.Bookmark, add, remove []string) bool {
+	changed := false
+
+	for _, t := range remove {
+		if bm.Tags.Contains(t) {
+			bm.Tags.Remove(t)
+			changed = true
+		}
+	}
+
+	for _, t := range add {
+		if !bm.Tags.Contains(t) {
+			bm.Tags.Append(t)
+			changed = true
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/tui"
+	"github.com/DanNixon/voile/web"
+)
+
+const (
+	DeadLinkTag       = "dead"
+	RedirectedLinkTag = "redirected"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [QUERY]",
+	Short: "Check bookmarks for dead links",
+	Long: `Requests the URL of every bookmark (or those selected by the same flags and query as the root command),
+recording the response on the bookmark and listing those that are dead or redirected.
+
+With --rewrite bookmarks that are permanently redirected are moved to their new URL,
+the old URL is kept as an alias so it still finds the bookmark. Combine with --list to
+rewrite using the results of the last check.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get options
+		listFlag, _ := cmd.Flags().GetBool(ListFlagName)
+		tagFlag, _ := cmd.Flags().GetBool(TagFlagName)
+		rewriteFlag, _ := cmd.Flags().GetBool(RewriteFlagName)
+		forceFlag, _ := cmd.Flags().GetBool(ForceFlagName)
+		concurrency, _ := cmd.Flags().GetInt(ConcurrencyFlagName)
+		timeout, _ := cmd.Flags().GetDuration(TimeoutFlagName)
+		hostDelay, _ := cmd.Flags().GetDuration(HostDelayFlagName)
+
+		// Load and filter bookmarks
+		p := FilterParamsFromFlags(cmd, args)
+		results := QueryBookmarks(&p)
+
+		// Only list results of previous checks
+		if listFlag && !rewriteFlag {
+			printLinkChecks(results)
+			return
+		}
+
+		var bmks db.BookmarkLibrary
+		var checked []*db.Bookmark
+
+		if listFlag {
+			// Rewrite using results of previous checks
+			bmks = ReadBookmarksFromFile()
+			for _, bm := range results {
+				if current, err := bmks.GetByNumber(bm.Number); err == nil {
+					checked = append(checked, current)
+				}
+			}
+		} else {
+			var urls []string
+			for _, bm := range results {
+				urls = append(urls, bm.Url.String())
+			}
+
+			// Check links without holding the lock, this may take some time
+			var mutex sync.Mutex
+			checks := make(map[string]db.LinkCheck)
+			checker := web.NewLinkChecker(web.DefaultFetcher.WithTimeout(timeout), concurrency, hostDelay)
+			checker.CheckAll(urls, func(s web.LinkStatus) {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				checks[s.Url] = NewLinkCheck(s)
+				fmt.Printf("\rChecked %d/%d", len(checks), len(urls))
+			})
+			fmt.Println()
+
+			// Record results, bookmarks removed or changed in the meantime are ignored
+			bmks = ReadBookmarksFromFile()
+			for _, bm := range results {
+				check, ok := checks[bm.Url.String()]
+				if !ok {
+					continue
+				}
+
+				current, err := bmks.GetByUrl(bm.Url.String())
+				if err != nil {
+					continue
+				}
+
+				current.LinkCheck = &check
+				if tagFlag {
+					TagLinkCheck(current)
+				}
+
+				checked = append(checked, current)
+			}
+		}
+
+		printLinkChecks(checked)
+
+		if rewriteFlag {
+			rewriteRedirects(&bmks, checked, forceFlag, tagFlag)
+		}
+
+		SaveBookmarksToFile(&bmks)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	AddFilteringFlags(checkCmd)
+
+	checkCmd.Flags().BoolP(ListFlagName, ListFlagShort, false, "List results of the last check without checking again")
+	checkCmd.Flags().Bool(TagFlagName, false, "Tag dead links with \""+DeadLinkTag+"\" and redirected links with \""+RedirectedLinkTag+"\"")
+	checkCmd.Flags().Bool(RewriteFlagName, false, "Update URLs of permanently redirected links, keeping the old URL as an alias")
+	checkCmd.Flags().Bool(ForceFlagName, false, "Rewrite URLs without confirmation")
+	checkCmd.Flags().Int(ConcurrencyFlagName, 8, "Number of links to check at the same time")
+	checkCmd.Flags().Duration(TimeoutFlagName, 15*time.Second, "Timeout for each request")
+	checkCmd.Flags().Duration(HostDelayFlagName, time.Second, "Minimum time between requests to the same host")
+}
+
+func NewLinkCheck(s web.LinkStatus) db.LinkCheck {
+	check := db.LinkCheck{
+		Checked:    time.Now(),
+		StatusCode: s.StatusCode,
+	}
+
+	if s.Err != nil {
+		check.Error = s.Err.Error()
+	}
+
+	if s.IsRedirected() {
+		check.RedirectUrl = s.FinalUrl
+		check.PermanentRedirect = s.Permanent
+	}
+
+	return check
+}
+
+// Sets the dead and redirected tags to match the last link check
+func TagLinkCheck(bm *db.Bookmark) {
+	bm.Tags.Remove(DeadLinkTag)
+	bm.Tags.Remove(RedirectedLinkTag)
+
+	if bm.LinkCheck.IsDead() {
+		bm.Tags.Append(DeadLinkTag)
+	} else if bm.LinkCheck.IsRedirected() {
+		bm.Tags.Append(RedirectedLinkTag)
+	}
+}
+
+func FormatLinkCheck(bm *db.Bookmark) string {
+	check := bm.LinkCheck
+
+	var status string
+	if len(check.Error) > 0 {
+		status = check.Error
+	} else {
+		status = strconv.Itoa(check.StatusCode) + " " + http.StatusText(check.StatusCode)
+	}
+
+	var statusStr aurora.Value
+	if check.IsDead() {
+		statusStr = aurora.Red(status)
+	} else {
+		statusStr = aurora.Green(status)
+	}
+
+	retVal := fmt.Sprintf("[%s] %s %s",
+		aurora.Bold(aurora.Cyan(strconv.Itoa(bm.Number))), aurora.Brown(bm.Url.String()), statusStr)
+
+	if check.IsRedirected() {
+		kind := "temporary"
+		if check.PermanentRedirect {
+			kind = "permanent"
+		}
+		retVal += fmt.Sprintf("\n  %s %s (%s)", aurora.Red("->"), aurora.Brown(check.RedirectUrl), kind)
+	}
+
+	return retVal
+}
+
+// Moves permanently redirected bookmarks to their final URL
+func rewriteRedirects(bmks *db.BookmarkLibrary, bms []*db.Bookmark, force, tag bool) {
+	for _, bm := range bms {
+		if bm.LinkCheck == nil || !bm.LinkCheck.PermanentRedirect || bm.LinkCheck.IsDead() {
+			continue
+		}
+
+		target := bm.LinkCheck.RedirectUrl
+
+		// Another bookmark may already have the new URL
+		if existing, err := bmks.GetByUrl(target); err == nil && existing.Number != bm.Number {
+			fmt.Printf("[%s] %s already bookmarked as [%s], not rewritten\n",
+				aurora.Bold(aurora.Cyan(strconv.Itoa(bm.Number))), aurora.Brown(target),
+				aurora.Bold(aurora.Cyan(strconv.Itoa(existing.Number))))
+			continue
+		}
+
+		after := *bm
+		if err := after.Url.Parse(target); err != nil {
+			continue
+		}
+		fmt.Printf("[%s] %s\n%s", aurora.Bold(aurora.Cyan(strconv.Itoa(bm.Number))), bm.Name, FormatBookmarkDiff(bm, &after))
+
+		rewrite := force
+		if !rewrite {
+			rewrite, _ = tui.Confirm("Rewrite URL?")
+		}
+		if !rewrite {
+			continue
+		}
+
+		err := bmks.MoveUrl(bm.Number, target)
+		if err != nil {
+			fmt.Println(aurora.Red(err.Error()))
+			continue
+		}
+
+		// The new URL is where the redirect ended up
+		bm.LinkCheck.RedirectUrl = ""
+		bm.LinkCheck.PermanentRedirect = false
+		if tag {
+			TagLinkCheck(bm)
+		}
+	}
+}
+
+func printLinkChecks(bms []*db.Bookmark) {
+	dead := 0
+	redirected := 0
+
+	for _, bm := range bms {
+		if bm.LinkCheck == nil {
+			continue
+		}
+
+		if bm.LinkCheck.IsDead() {
+			dead++
+		} else if bm.LinkCheck.IsRedirected() {
+			redirected++
+		} else {
+			continue
+		}
+
+		fmt.Println(FormatLinkCheck(bm))
+	}
+
+	fmt.Printf("%d dead, %d redirected\n", dead, redirected)
+}
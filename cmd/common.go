@@ -1,13 +1,10 @@
 package cmd
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -22,10 +19,19 @@ import (
 	"github.com/DanNixon/voile/db"
 
 	"github.com/DanNixon/voile/tui"
+	"github.com/DanNixon/voile/web"
 )
 
 const (
 	BookmarksFileConfigEntry = "bookmark_file"
+	StorageConfigEntry       = "storage"
+
+	HttpTimeoutConfigEntry   = "http_timeout"
+	HttpUserAgentConfigEntry = "http_user_agent"
+	HttpProxyConfigEntry     = "http_proxy"
+	HttpMaxSizeConfigEntry   = "http_max_size"
+
+	ApiTokenConfigEntry = "api_token"
 )
 
 const (
@@ -52,6 +58,9 @@ const (
 	TitleNameFlagName  = "autoname"
 	TitleNameFlagShort = "a"
 
+	MetadataFlagName  = "metadata"
+	MetadataFlagShort = "m"
+
 	UrlFlagName  = "url"
 	UrlFlagShort = "u"
 
@@ -60,8 +69,51 @@ const (
 
 	JsonFlagName  = "json"
 	JsonFlagShort = "j"
+
+	RankFlagName  = "rank"
+	RankFlagShort = "r"
+
+	StorageFlagName = "storage"
+
+	FormatFlagName  = "format"
+	FormatFlagShort = "f"
+
+	OutputFlagName  = "output"
+	OutputFlagShort = "o"
+
+	DryRunFlagName = "dry-run"
+
+	ListFlagName  = "list"
+	ListFlagShort = "l"
+
+	TagFlagName = "tag"
+
+	RewriteFlagName = "rewrite"
+
+	RefreshFlagName = "refresh"
+
+	AddTagsFlagName    = "add-tags"
+	RemoveTagsFlagName = "remove-tags"
+	DeleteFlagName     = "delete"
+
+	ContentFlagName = "content"
+
+	ArchiveFlagName  = "archive"
+	ArchivedFlagName = "archived"
+
+	ConcurrencyFlagName = "concurrency"
+	TimeoutFlagName     = "timeout"
+	HostDelayFlagName   = "host-delay"
+
+	AddressFlagName = "address"
+	HostFlagName    = "host"
+
+	RemoteFlagName  = "remote"
+	InstallFlagName = "install"
 )
 
+var Store db.Store
+
 func CheckError(err error) {
 	if err != nil {
 		fmt.Println(err)
@@ -70,6 +122,11 @@ func CheckError(err error) {
 }
 
 func FormatBookmark(bm *db.Bookmark, index int) string {
+	return FormatBookmarkWithSnippet(bm, index, "")
+}
+
+// Includes a passage of page content matching a search
+func FormatBookmarkWithSnippet(bm *db.Bookmark, index int, snippet string) string {
 	// Generate name
 	var nameStr aurora.Value
 	if bm.HasName() {
@@ -97,6 +154,20 @@ func FormatBookmark(bm *db.Bookmark, index int) string {
 			r.Replace(bm.Description))
 	}
 
+	// Matching page content (if searched)
+	if len(snippet) > 0 {
+		retVal += fmt.Sprintf("\n  %s %s", aurora.Red("\""), FormatSnippet(snippet))
+	}
+
+	// Author and published date (if known)
+	if bm.Metadata != nil && (len(bm.Metadata.Author) > 0 || bm.Metadata.HasPublished()) {
+		byline := bm.Metadata.Author
+		if bm.Metadata.HasPublished() {
+			byline = strings.TrimSpace(byline + " " + bm.Metadata.Published.Format(db.QueryDateFormat))
+		}
+		retVal += fmt.Sprintf("\n  %s %s", aurora.Red("@"), aurora.Cyan(byline))
+	}
+
 	// Added timestamp
 	retVal += fmt.Sprintf("\n  %s %s", aurora.Red("+"),
 		aurora.Cyan(bm.WhenAdded.Format(time.UnixDate)))
@@ -104,17 +175,64 @@ func FormatBookmark(bm *db.Bookmark, index int) string {
 	return retVal
 }
 
-func EditBookmarkInEditor(bmks *db.BookmarkLibrary, bm *db.Bookmark) {
-	var err error
+func FormatMergeResult(bm *db.Bookmark, result db.MergeResult) string {
+	var marker aurora.Value
+	switch result {
+	case db.MergeAdded:
+		marker = aurora.Green("+ added ")
+	case db.MergeMerged:
+		marker = aurora.Brown("~ merged ")
+	default:
+		marker = aurora.Blue("= skipped")
+	}
 
-	// Generate default bookmark string
-	bmStr := bm.FormatAsInteractiveFileString()
+	return fmt.Sprintf("%s [%s] %s",
+		marker, aurora.Bold(aurora.Cyan(strconv.Itoa(bm.Number))), aurora.Brown(bm.Url.String()))
+}
 
-	// Add existing tags commented at end of string
+// Lists the fields that differ between two versions of a bookmark
+func FormatBookmarkDiff(before, after *db.Bookmark) string {
+	retVal := ""
+
+	diffField := func(name, old, new string) {
+		if old == new {
+			return
+		}
+		if len(old) > 0 {
+			retVal += fmt.Sprintf("    %s %s: %s\n", aurora.Red("-"), name, old)
+		}
+		if len(new) > 0 {
+			retVal += fmt.Sprintf("    %s %s: %s\n", aurora.Green("+"), name, new)
+		}
+	}
+
+	diffField("title", before.Name, after.Name)
+	diffField("url", before.Url.String(), after.Url.String())
+	diffField("tags", before.Tags.String(), after.Tags.String())
+	diffField("description", before.Description, after.Description)
+
+	return retVal
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// Appends existing tags as comments, to help when choosing tags
+func withExistingTags(bmks *db.BookmarkLibrary, bmStr string) string {
 	bmStr += "# Existing tags:\n"
 	for _, t := range bmks.GetAllTags().Tags.Tags {
 		bmStr += "# " + t + "\n"
 	}
+	return bmStr
+}
+
+func EditBookmarkInEditor(bmks *db.BookmarkLibrary, bm *db.Bookmark, aliases db.TagAliases) {
+	var err error
+
+	// Generate default bookmark string
+	bmStr := withExistingTags(bmks, bm.FormatAsInteractiveFileString())
 
 	// Launch editor with existing bookmark data
 	bmStr, err = tui.EditText(bmStr)
@@ -123,6 +241,84 @@ func EditBookmarkInEditor(bmks *db.BookmarkLibrary, bm *db.Bookmark) {
 	// Update bookmark with modifications
 	err = bm.UpdateFromInteractiveFileString(bmStr)
 	CheckError(err)
+
+	aliases.Apply(&bm.Tags)
+}
+
+// Edits several bookmarks in a single editor session, sections added in the
+// editor become new bookmarks and removing a section offers to delete the
+// bookmark. Returns the numbers of the bookmarks that remain, including added
+// ones.
+func EditBookmarksInEditor(bmks *db.BookmarkLibrary, bms []*db.Bookmark, aliases db.TagAliases) []int {
+	var err error
+
+	var numbers []int
+	for _, bm := range bms {
+		numbers = append(numbers, bm.Number)
+	}
+
+	// Launch editor with existing bookmark data, reopening it with the edited
+	// text if that is invalid so nothing typed is lost
+	var changes db.InteractiveFileChanges
+	bmStr := withExistingTags(bmks, db.FormatAsInteractiveFile(bms))
+	for {
+		bmStr, err = tui.EditText(bmStr)
+		CheckError(err)
+
+		// Update bookmarks with modifications
+		changes, err = bmks.UpdateFromInteractiveFile(bmStr, numbers)
+		if err == nil {
+			break
+		}
+
+		fmt.Println(err)
+		again, _ := tui.Confirm("Edit again?")
+		if !again {
+			os.Exit(1)
+		}
+	}
+
+	for _, n := range append(changes.Updated, changes.Added...) {
+		bm, _ := bmks.GetByNumber(n)
+		aliases.Apply(&bm.Tags)
+	}
+
+	// Bookmarks are only deleted when asked
+	deleted := make(map[int]bool)
+	if len(changes.Removed) > 0 {
+		for i, n := range changes.Removed {
+			bm, _ := bmks.GetByNumber(n)
+			fmt.Println(FormatBookmark(bm, i))
+		}
+
+		rm, _ := tui.Confirm(fmt.Sprintf("Sections for %d bookmark(s) were removed, delete them?", len(changes.Removed)))
+		if rm {
+			for _, n := range changes.Removed {
+				err = bmks.DeleteByNumber(n)
+				CheckError(err)
+				deleted[n] = true
+			}
+		}
+	}
+
+	var remaining []int
+	for _, n := range append(numbers, changes.Added...) {
+		if !deleted[n] {
+			remaining = append(remaining, n)
+		}
+	}
+
+	return remaining
+}
+
+func bookmarksByNumber(bmks *db.BookmarkLibrary, numbers []int) []*db.Bookmark {
+	var bms []*db.Bookmark
+	for _, n := range numbers {
+		bm, err := bmks.GetByNumber(n)
+		CheckError(err)
+		bms = append(bms, bm)
+	}
+	return bms
 }
 
 func IsValidBookmarkNumberArgument(cmd *cobra.Command, args []string) error {
@@ -130,67 +326,93 @@ func IsValidBookmarkNumberArgument(cmd *cobra.Command, args []string) error {
 		return errors.New("requires exactly least one arg")
 	}
 
-	index, err := strconv.Atoi(args[0])
+	return checkBookmarkNumber(args[0])
+}
+
+// Numbers start from 1, as in the headers of the editor
+func checkBookmarkNumber(arg string) error {
+	number, err := strconv.Atoi(arg)
 	if err != nil {
 		return err
 	}
 
-	if index < 0 {
-		return errors.New("Bookmark number must be >= 0")
+	if number < 1 {
+		return errors.New("Bookmark number must be >= 1")
 	}
 
 	return nil
 }
 
 func ReadBookmarksFromFile() db.BookmarkLibrary {
-	var bmks db.BookmarkLibrary
-
-	// Read entire JSON file to string
-	raw, err := ioutil.ReadFile(viper.GetString(BookmarksFileConfigEntry))
+	// Lock is held until the bookmarks are saved (or the process exits)
+	err := Store.Lock()
 	CheckError(err)
 
-	// Load bookmarks from JSON
-	json.Unmarshal(raw, &(bmks.Bookmarks))
+	return ReadBookmarksFromFileReadOnly()
+}
 
-	// Validate the loaded data
-	err = bmks.Verify()
+// For commands that never save changes back to the library
+func ReadBookmarksFromFileReadOnly() db.BookmarkLibrary {
+	bmks, err := Store.Load()
 	CheckError(err)
 
-	sort.Sort(&bmks)
-
 	return bmks
 }
 
 func SaveBookmarksToFile(bmks *db.BookmarkLibrary) {
-	// Validate the bookmarks before saving
-	err := bmks.Verify()
+	// Write bookmarks to the store
+	err := Store.Save(bmks)
 	CheckError(err)
 
-	// Write bookmarks to indented JSON string
-	raw, err := json.MarshalIndent(bmks.Bookmarks, "", "  ")
-	CheckError(err)
+	commitAndUnlockBookmarkFile()
+}
 
-	// Write JSON string to file
-	err = ioutil.WriteFile(
-		viper.GetString(BookmarksFileConfigEntry), []byte(raw), 0644)
+// Saves changes to tags along with the aliases to them
+func SaveBookmarksAndTagAliasesToFile(bmks *db.BookmarkLibrary, aliases db.TagAliases) {
+	// Write bookmarks first, so aliases are left as they were if the library
+	// is invalid or was changed by something else
+	err := Store.Save(bmks)
 	CheckError(err)
 
+	SaveTagAliases(aliases)
+
+	commitAndUnlockBookmarkFile()
+}
+
+func commitAndUnlockBookmarkFile() {
 	// Git commit
-	err = CommitChangesToBookmarkFile()
+	err := CommitChangesToBookmarkFile()
+	CheckError(err)
+
+	err = Store.Unlock()
 	CheckError(err)
 }
 
 func GetBookmarksFileParentDirectory() string {
-	filename := viper.GetString(BookmarksFileConfigEntry)
-	return filepath.Dir(filename)
+	fs, ok := Store.(db.FileStore)
+	if !ok {
+		return ""
+	}
+
+	return filepath.Dir(fs.Path())
 }
 
 func IsBookmarksFileInGitRepository() bool {
+	if _, ok := Store.(db.FileStore); !ok {
+		return false
+	}
+
 	_, err := git.PlainOpen(GetBookmarksFileParentDirectory())
 	return err == nil
 }
 
 func CommitChangesToBookmarkFile() error {
+	// Only stores backed by a file can be committed
+	fs, ok := Store.(db.FileStore)
+	if !ok {
+		return nil
+	}
+
 	gitDir := GetBookmarksFileParentDirectory()
 
 	repo, err := git.PlainOpen(gitDir)
@@ -203,7 +425,7 @@ func CommitChangesToBookmarkFile() error {
 		return err
 	}
 
-	file, err := filepath.Rel(gitDir, viper.GetString(BookmarksFileConfigEntry))
+	file, err := filepath.Rel(gitDir, fs.Path())
 	if err != nil {
 		return err
 	}
@@ -213,15 +435,41 @@ func CommitChangesToBookmarkFile() error {
 		return err
 	}
 
-	username, _ := gitconfig.Username()
-	email, _ := gitconfig.Email()
+	// Include tag aliases
+	if aliasesFile := db.TagAliasesForFile(fs.Path()); fileExists(aliasesFile) {
+		file, err := filepath.Rel(gitDir, aliasesFile)
+		if err != nil {
+			return err
+		}
+
+		_, err = wt.Add(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Include any page snapshots
+	archive := db.ArchiveForFile(fs.Path())
+	if fileExists(archive.Dir) {
+		dir, err := filepath.Rel(gitDir, archive.Dir)
+		if err != nil {
+			return err
+		}
+
+		_, err = wt.Add(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Nothing to commit if the library was saved unchanged
+	staged, err := hasStagedChanges(wt)
+	if err != nil || !staged {
+		return err
+	}
 
 	_, err = wt.Commit("voile auto commit", &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  username,
-			Email: email,
-			When:  time.Now(),
-		},
+		Author: commitSignature(),
 	})
 	if err != nil {
 		return err
@@ -230,24 +478,83 @@ func CommitChangesToBookmarkFile() error {
 	return nil
 }
 
+func hasStagedChanges(wt *git.Worktree) (bool, error) {
+	status, err := wt.Status()
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range status {
+		if s.Staging != git.Unmodified && s.Staging != git.Untracked {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Author of commits made by voile, from the Git config
+func commitSignature() *object.Signature {
+	username, _ := gitconfig.Username()
+	email, _ := gitconfig.Email()
+
+	return &object.Signature{
+		Name:  username,
+		Email: email,
+		When:  time.Now(),
+	}
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
-	cobra.OnInitialize(initBookmarksFile)
+	cobra.OnInitialize(initStore)
+	cobra.OnInitialize(initFetcher)
 }
 
 func initConfig() {
 	// Setup environment variable config options
 	viper.SetEnvPrefix("voile")
 	viper.BindEnv(BookmarksFileConfigEntry)
+	viper.BindEnv(StorageConfigEntry)
+	viper.BindEnv(HttpTimeoutConfigEntry)
+	viper.BindEnv(HttpUserAgentConfigEntry)
+	viper.BindEnv(HttpProxyConfigEntry)
+	viper.BindEnv(HttpMaxSizeConfigEntry)
+	viper.BindEnv(ApiTokenConfigEntry)
 
 	// Set default bookmarks file
 	viper.SetDefault(BookmarksFileConfigEntry, "bookmarks.json")
+
+	// Set default network options
+	viper.SetDefault(HttpTimeoutConfigEntry, web.DefaultTimeout)
+	viper.SetDefault(HttpUserAgentConfigEntry, web.DefaultUserAgent)
+	viper.SetDefault(HttpMaxSizeConfigEntry, web.DefaultMaxSize)
+}
+
+func initStore() {
+	// Allow a store to be provided before commands are run (e.g. in tests)
+	if Store != nil {
+		return
+	}
+
+	var err error
+	Store, err = db.NewStore(
+		viper.GetString(StorageConfigEntry),
+		viper.GetString(BookmarksFileConfigEntry))
+	CheckError(err)
 }
 
-func initBookmarksFile() {
-	// Create an empty bookmarks file if one does not already exist
-	if _, err := os.Stat(viper.GetString(BookmarksFileConfigEntry)); err != nil {
-		var bmks db.BookmarkLibrary
-		SaveBookmarksToFile(&bmks)
+func initFetcher() {
+	f := web.NewFetcher()
+	f.Client.Timeout = viper.GetDuration(HttpTimeoutConfigEntry)
+	f.UserAgent = viper.GetString(HttpUserAgentConfigEntry)
+	f.MaxSize = viper.GetInt64(HttpMaxSizeConfigEntry)
+
+	// Otherwise the usual proxy environment variables are used
+	if proxy := viper.GetString(HttpProxyConfigEntry); len(proxy) > 0 {
+		err := f.SetProxy(proxy)
+		CheckError(err)
 	}
+
+	web.DefaultFetcher = f
 }
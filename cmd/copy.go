@@ -17,7 +17,7 @@ var copyCmd = &cobra.Command{
 		bookmarkNumber, _ := strconv.Atoi(args[0])
 
 		// Load bookmarks from file
-		bmks := ReadBookmarksFromFile()
+		bmks := ReadBookmarksFromFileReadOnly()
 
 		// Get bookmark entry
 		bm, err := bmks.GetByNumber(bookmarkNumber)
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/tui"
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find and merge duplicate bookmarks",
+	Long: `Lists groups of bookmarks whose URLs are likely the same page (ignoring the scheme, a leading www,
+a trailing slash, the fragment and tracking parameters) and interactively merges each group.
+
+Merged bookmarks keep the URL of the chosen bookmark, with the other URLs kept as aliases. Tags are
+combined and descriptions concatenated.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listFlag, _ := cmd.Flags().GetBool(ListFlagName)
+
+		// Only list duplicates
+		if listFlag {
+			bmks := ReadBookmarksFromFileReadOnly()
+			for i, group := range bmks.FindDuplicates() {
+				if i > 0 {
+					fmt.Println()
+				}
+				for j, bm := range group {
+					fmt.Println(FormatBookmark(bm, j))
+				}
+			}
+			return
+		}
+
+		// Load bookmarks from file
+		bmks := ReadBookmarksFromFile()
+
+		// Collect numbers first, merging invalidates the groups
+		var groups [][]int
+		for _, group := range bmks.FindDuplicates() {
+			var numbers []int
+			for _, bm := range group {
+				numbers = append(numbers, bm.Number)
+			}
+			groups = append(groups, numbers)
+		}
+
+		for i, numbers := range groups {
+			fmt.Printf("%s\n", aurora.Bold(fmt.Sprintf("Duplicates %d/%d", i+1, len(groups))))
+			for j, number := range numbers {
+				bm, err := bmks.GetByNumber(number)
+				CheckError(err)
+				fmt.Println(FormatBookmark(bm, j))
+			}
+
+			keep, ok := promptBookmarkToKeep(numbers)
+			if !ok {
+				fmt.Println("Not merged.")
+				fmt.Println()
+				continue
+			}
+
+			var others []int
+			for _, number := range numbers {
+				if number != keep {
+					others = append(others, number)
+				}
+			}
+
+			err := bmks.MergeDuplicates(keep, others)
+			CheckError(err)
+
+			bm, err := bmks.GetByNumber(keep)
+			CheckError(err)
+			fmt.Println(FormatBookmark(bm, 0))
+			fmt.Println()
+		}
+
+		// Save bookmarks back to file
+		SaveBookmarksToFile(&bmks)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+
+	dedupeCmd.Flags().BoolP(ListFlagName, ListFlagShort, false, "List duplicates without merging")
+}
+
+// Asks which bookmark of a group to merge the others into, the first by default
+func promptBookmarkToKeep(numbers []int) (int, bool) {
+	for {
+		answer, err := tui.Prompt(fmt.Sprintf("Merge into bookmark (default %d, s to skip)", numbers[0]))
+		CheckError(err)
+
+		switch answer {
+		case "":
+			return numbers[0], true
+		case "s", "S":
+			return 0, false
+		}
+
+		if number, err := strconv.Atoi(answer); err == nil {
+			for _, n := range numbers {
+				if n == number {
+					return number, true
+				}
+			}
+		}
+
+		fmt.Println(aurora.Red("Not one of the duplicate bookmarks"))
+	}
+}
@@ -1,39 +1,89 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
 	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
 )
 
 var editCmd = &cobra.Command{
-	Use:   "edit N",
-	Short: "Edit a bookmark",
-	Long:  `Opens a text editor that allows editing a specific bookmark, identified by unique number N.`,
-	Args:  IsValidBookmarkNumberArgument,
+	Use:   "edit [N...]",
+	Short: "Edit bookmarks",
+	Long: `Opens a text editor that allows editing bookmarks, identified by unique numbers N or selected by the same flags as the root command, e.g.:
+  voile edit 3 7 12
+  voile edit --tags news
+
+Each bookmark is a section headed with its number. Adding a section without a number creates a new bookmark and removing a section offers to delete the bookmark.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		for _, a := range args {
+			if err := checkBookmarkNumber(a); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		// Get bookmark number
-		bookmarkNumber, _ := strconv.Atoi(args[0])
+		p := FilterParamsFromFlags(cmd, nil)
+		if len(args) == 0 && p.IsEmpty() {
+			CheckError(errors.New("Give bookmark numbers or filter flags"))
+		}
 
 		// Load bookmarks from file
 		bmks := ReadBookmarksFromFile()
 
-		// Get existing bookmark entry
-		bm, err := bmks.GetByNumber(bookmarkNumber)
-		CheckError(err)
+		// Get existing bookmark entries
+		var bms []*db.Bookmark
+		for _, a := range args {
+			bookmarkNumber, _ := strconv.Atoi(a)
+			bm, err := bmks.GetByNumber(bookmarkNumber)
+			CheckError(err)
+			bms = append(bms, bm)
+		}
+
+		if !p.IsEmpty() {
+			results, err := FilterBookmarks(&p, &bmks)
+			CheckError(err)
+
+			for _, bm := range results {
+				if !containsBookmark(bms, bm) {
+					bms = append(bms, bm)
+				}
+			}
+		}
 
-		// Edit bookmark
-		EditBookmarkInEditor(&bmks, bm)
+		if len(bms) == 0 {
+			fmt.Println("No bookmarks selected.")
+			return
+		}
+
+		// Edit bookmarks
+		numbers := EditBookmarksInEditor(&bmks, bms, LoadTagAliases())
 
 		// Save bookmarks back to file
 		SaveBookmarksToFile(&bmks)
 
-		// Print bookmark to console
-		fmt.Println(FormatBookmark(bm, 0))
+		// Print bookmarks to console
+		for i, bm := range bookmarksByNumber(&bmks, numbers) {
+			fmt.Println(FormatBookmark(bm, i))
+		}
 	},
 }
 
+func containsBookmark(bms []*db.Bookmark, bm *db.Bookmark) bool {
+	for _, b := range bms {
+		if b.Number == bm.Number {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
 	rootCmd.AddCommand(editCmd)
+
+	AddFilteringFlags(editCmd)
 }
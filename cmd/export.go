@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/exporter"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [QUERY]",
+	Short: "Export bookmarks to a file",
+	Long: `Exports bookmarks for use in a browser or for publishing.
+Bookmarks are selected using the same flags and query as the root command, with no filters exporting the entire library.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get exporter
+		format, _ := cmd.Flags().GetString(FormatFlagName)
+		exp, err := exporter.Get(format)
+		CheckError(err)
+
+		// Load and filter bookmarks
+		p := FilterParamsFromFlags(cmd, args)
+		results := QueryBookmarks(&p)
+
+		var bookmarks []db.Bookmark
+		for _, bm := range results {
+			bookmarks = append(bookmarks, *bm)
+		}
+
+		// Write to console
+		if !cmd.Flags().Changed(OutputFlagName) {
+			err = exp.Export(os.Stdout, bookmarks)
+			CheckError(err)
+			return
+		}
+
+		// Or to file, which is only complete once closed
+		filename, _ := cmd.Flags().GetString(OutputFlagName)
+		f, err := os.Create(filename)
+		CheckError(err)
+
+		err = exp.Export(f, bookmarks)
+		if err != nil {
+			f.Close()
+			CheckError(err)
+		}
+
+		err = f.Close()
+		CheckError(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	AddFilteringFlags(exportCmd)
+
+	exportCmd.Flags().StringP(FormatFlagName, FormatFlagShort, "netscape",
+		"Format of the file ("+strings.Join(exporter.Formats(), ", ")+")")
+	exportCmd.Flags().StringP(OutputFlagName, OutputFlagShort, "", "File to write to (default is standard output)")
+}
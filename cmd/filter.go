@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+)
+
+type FilteringOptions struct {
+	Cases []FilterCase
+}
+
+type FilterCase struct {
+	UserCares bool
+	Func      func(*db.Bookmark) bool
+}
+
+// Parameters for selecting bookmarks, shared by every command that operates
+// on the results of a query
+type FilterParams struct {
+	Number      int
+	Tags        []string
+	Name        string
+	Url         string
+	Description string
+	Content     string
+
+	HasNumber      bool
+	HasTags        bool
+	HasName        bool
+	HasUrl         bool
+	HasDescription bool
+	HasContent     bool
+
+	// Boolean query, or free text terms if Rank is set
+	Query string
+	Rank  bool
+
+	// Passages of page content that matched, by URL
+	Snippets map[string]string
+}
+
+func AddFilteringFlags(cmd *cobra.Command) {
+	cmd.Flags().IntP(NumberFlagName, NumberFlagShort, 0, "Get bookmark by number")
+	cmd.Flags().StringSliceP(TagsFlagName, TagsFlagShort, []string{}, "Get bookmarks by tags")
+	cmd.Flags().StringP(NameFlagName, "s", "", "Search in name")
+	cmd.Flags().StringP(UrlFlagName, UrlFlagShort, "", "Search in URL")
+	cmd.Flags().StringP(DescFlagName, DescFlagShort, "", "Search in description")
+	cmd.Flags().String(ContentFlagName, "", "Search in page content (see \"voile index\")")
+
+	cmd.Flags().BoolP(RankFlagName, RankFlagShort, false, "Treat query as free text and order results by relevance")
+}
+
+func FilterParamsFromFlags(cmd *cobra.Command, args []string) FilterParams {
+	var p FilterParams
+
+	p.Number, _ = cmd.Flags().GetInt(NumberFlagName)
+	p.Tags, _ = cmd.Flags().GetStringSlice(TagsFlagName)
+	p.Name, _ = cmd.Flags().GetString(NameFlagName)
+	p.Url, _ = cmd.Flags().GetString(UrlFlagName)
+	p.Description, _ = cmd.Flags().GetString(DescFlagName)
+	p.Content, _ = cmd.Flags().GetString(ContentFlagName)
+
+	p.HasNumber = cmd.Flags().Changed(NumberFlagName)
+	p.HasTags = cmd.Flags().Changed(TagsFlagName)
+	p.HasName = cmd.Flags().Changed(NameFlagName)
+	p.HasUrl = cmd.Flags().Changed(UrlFlagName)
+	p.HasDescription = cmd.Flags().Changed(DescFlagName)
+	p.HasContent = cmd.Flags().Changed(ContentFlagName)
+
+	p.Query = strings.Join(args, " ")
+	p.Rank, _ = cmd.Flags().GetBool(RankFlagName)
+
+	return p
+}
+
+// Reads the same filters as the flags from URL query parameters, with the
+// query in q and each tag in a tag parameter
+func FilterParamsFromValues(values url.Values) (FilterParams, error) {
+	var p FilterParams
+
+	if n := values.Get(NumberFlagName); len(n) > 0 {
+		var err error
+		p.Number, err = strconv.Atoi(n)
+		if err != nil {
+			return p, errors.New(fmt.Sprintf("Invalid bookmark number \"%s\"", n))
+		}
+		p.HasNumber = true
+	}
+
+	p.Tags, p.HasTags = values[TagFlagName]
+	p.Name, p.HasName = getValue(values, NameFlagName)
+	p.Url, p.HasUrl = getValue(values, UrlFlagName)
+	p.Description, p.HasDescription = getValue(values, DescFlagName)
+	p.Content, p.HasContent = getValue(values, ContentFlagName)
+
+	p.Query = values.Get("q")
+	p.Rank, _ = strconv.ParseBool(values.Get(RankFlagName))
+
+	return p, nil
+}
+
+func getValue(values url.Values, key string) (string, bool) {
+	if v, ok := values[key]; ok && len(v) > 0 {
+		return v[0], true
+	}
+	return "", false
+}
+
+func (p *FilterParams) IsEmpty() bool {
+	return !p.HasNumber && !p.HasTags && !p.HasName && !p.HasUrl &&
+		!p.HasDescription && !p.HasContent && len(strings.TrimSpace(p.Query)) == 0
+}
+
+func (p *FilterParams) Options() (FilteringOptions, error) {
+	// Parse query
+	var query db.Predicate
+	if len(strings.TrimSpace(p.Query)) > 0 && !p.Rank {
+		var err error
+		query, err = db.ParseQuery(p.Query)
+		if err != nil {
+			return FilteringOptions{}, err
+		}
+	}
+
+	return FilteringOptions{
+		[]FilterCase{
+			{
+				p.HasNumber,
+				func(b *db.Bookmark) bool { return b.Number == p.Number },
+			},
+			{
+				p.HasTags,
+				func(b *db.Bookmark) bool { return b.Tags.HasAllTags(p.Tags) },
+			},
+			{
+				p.HasName,
+				func(b *db.Bookmark) bool { return b.NameMatches(p.Name) },
+			},
+			{
+				p.HasUrl,
+				func(b *db.Bookmark) bool { return b.UrlMatches(p.Url) },
+			},
+			{
+				p.HasDescription,
+				func(b *db.Bookmark) bool { return b.DescriptionMatches(p.Description) },
+			},
+			{
+				query != nil,
+				func(b *db.Bookmark) bool { return query(b) },
+			},
+		},
+	}, nil
+}
+
+func (p *FilterParams) SearchTerms() db.SearchTerms {
+	var terms db.SearchTerms
+	if p.HasTags {
+		terms.Tags = p.Tags
+	}
+	if p.HasName {
+		terms.Name = p.Name
+	}
+	if p.HasUrl {
+		terms.Url = p.Url
+	}
+	if p.HasDescription {
+		terms.Description = p.Description
+	}
+	return terms
+}
+
+// Returns the matching bookmarks in the library, in the order they should be
+// presented
+func FilterBookmarks(p *FilterParams, bmks *db.BookmarkLibrary) ([]*db.Bookmark, error) {
+	d, err := p.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	// Search page content
+	contentRank := make(map[string]int)
+	if p.HasContent {
+		matches, err := SearchContent(p.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Snippets = make(map[string]string)
+		for i, m := range matches {
+			contentRank[m.Url] = i
+			p.Snippets[m.Url] = m.Snippet
+		}
+
+		d.Cases = append(d.Cases, FilterCase{
+			true,
+			func(b *db.Bookmark) bool {
+				_, ok := contentRank[b.Url.String()]
+				return ok
+			},
+		})
+	}
+
+	// Order by relevance to the free text query if requested
+	var candidates []*db.Bookmark
+	if terms := strings.Fields(p.Query); len(terms) > 0 && p.Rank {
+		for _, r := range bmks.Rank(terms) {
+			candidates = append(candidates, r.Bookmark)
+		}
+	} else {
+		for i := range bmks.Bookmarks {
+			candidates = append(candidates, &(bmks.Bookmarks[i]))
+		}
+
+		// Otherwise by relevance to the content search
+		if p.HasContent {
+			sort.SliceStable(candidates, func(i, j int) bool {
+				return contentRank[candidates[i].Url.String()] < contentRank[candidates[j].Url.String()]
+			})
+		}
+	}
+
+	// Filter bookmarks
+	var results []*db.Bookmark
+	for _, bm := range candidates {
+		if includeBookmark(&d, bm) {
+			results = append(results, bm)
+		}
+	}
+
+	return results, nil
+}
+
+// Loads and filters bookmarks for commands that do not modify the library,
+// using the store's index to narrow them down if possible
+func QueryBookmarks(p *FilterParams) []*db.Bookmark {
+	var bmks db.BookmarkLibrary
+	if s, ok := Store.(db.SearchableStore); ok {
+		var err error
+		bmks, err = s.Search(p.SearchTerms())
+		CheckError(err)
+	} else {
+		bmks = ReadBookmarksFromFileReadOnly()
+	}
+
+	results, err := FilterBookmarks(p, &bmks)
+	CheckError(err)
+
+	return results
+}
+
+func includeBookmark(query *FilteringOptions, bm *db.Bookmark) bool {
+	res := true
+	for _, q := range query.Cases {
+		if q.UserCares {
+			res = res && q.Func(bm)
+		}
+	}
+	return res
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/importer"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Import bookmarks from a file",
+	Long: `Imports bookmarks exported from a browser or another bookmarking service.
+Bookmarks with a URL already in the library have any new tags, name or description merged into the existing bookmark.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get importer
+		format, _ := cmd.Flags().GetString(FormatFlagName)
+		imp, err := importer.Get(format)
+		CheckError(err)
+
+		// Read bookmarks to import
+		f, err := os.Open(args[0])
+		CheckError(err)
+		defer f.Close()
+
+		imported, err := imp.Import(f)
+		CheckError(err)
+
+		// Load bookmarks from file
+		dryRun, _ := cmd.Flags().GetBool(DryRunFlagName)
+		var bmks db.BookmarkLibrary
+		if dryRun {
+			bmks = ReadBookmarksFromFileReadOnly()
+		} else {
+			bmks = ReadBookmarksFromFile()
+		}
+
+		// Merge imported bookmarks into library
+		counts := make(map[db.MergeResult]int)
+		for _, i := range imported {
+			// Keep original to show what changed
+			var before db.Bookmark
+			if existing, err := bmks.GetByUrl(i.Url.String()); err == nil {
+				before = *existing
+				before.Tags.Tags = append([]string{}, existing.Tags.Tags...)
+			}
+
+			bm, result := bmks.Merge(i)
+			counts[result]++
+
+			fmt.Println(FormatMergeResult(bm, result))
+			if result != db.MergeSkipped {
+				fmt.Print(FormatBookmarkDiff(&before, bm))
+			}
+		}
+
+		fmt.Printf("\nAdded %d, merged %d, skipped %d\n",
+			counts[db.MergeAdded], counts[db.MergeMerged], counts[db.MergeSkipped])
+
+		if dryRun {
+			fmt.Println("Dry run, library not modified")
+			return
+		}
+
+		// Save bookmarks back to file
+		SaveBookmarksToFile(&bmks)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringP(FormatFlagName, FormatFlagShort, "netscape",
+		"Format of the file ("+strings.Join(importer.Formats(), ", ")+")")
+	importCmd.Flags().Bool(DryRunFlagName, false, "Show changes without modifying the library")
+}
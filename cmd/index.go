@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/web"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index [QUERY]",
+	Short: "Index the content of bookmarked pages",
+	Long: `Fetches bookmarked pages (or those selected by the same flags and query as the root command) and
+indexes their text, so they can be searched with "voile --content".
+
+Pages already indexed are skipped unless --refresh is given. When indexing the whole library, pages
+no longer bookmarked are removed from the index.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		refreshFlag, _ := cmd.Flags().GetBool(RefreshFlagName)
+
+		// Load and filter bookmarks
+		p := FilterParamsFromFlags(cmd, args)
+		results := QueryBookmarks(&p)
+
+		index := OpenContentIndex()
+		defer index.Close()
+
+		indexed, err := index.Indexed()
+		CheckError(err)
+
+		// Remove pages that are no longer bookmarked
+		if p.IsEmpty() {
+			bookmarked := make(map[string]bool)
+			for _, bm := range results {
+				bookmarked[bm.Url.String()] = true
+			}
+
+			for url := range indexed {
+				if !bookmarked[url] {
+					err = index.Delete(url)
+					CheckError(err)
+				}
+			}
+		}
+
+		for i, bm := range results {
+			url := bm.Url.String()
+			if _, ok := indexed[url]; ok && !refreshFlag {
+				continue
+			}
+
+			fmt.Printf("[%d/%d] %s\n", i+1, len(results), aurora.Brown(url))
+
+			text, err := web.FetchText(bm.Url.Url)
+			if err != nil {
+				fmt.Println(aurora.Red(err.Error()))
+				continue
+			}
+
+			err = index.Put(url, text)
+			CheckError(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+
+	AddFilteringFlags(indexCmd)
+
+	indexCmd.Flags().Bool(RefreshFlagName, false, "Index pages again even if already indexed")
+}
+
+func contentIndexFilename() (string, error) {
+	fs, ok := Store.(db.FileStore)
+	if !ok {
+		return "", errors.New("Content indexing requires a library stored in a file")
+	}
+
+	return db.ContentIndexForFile(fs.Path()), nil
+}
+
+func OpenContentIndex() *db.ContentIndex {
+	filename, err := contentIndexFilename()
+	CheckError(err)
+
+	index, err := db.OpenContentIndex(filename)
+	CheckError(err)
+
+	return index
+}
+
+// Finds bookmarked pages containing the query, best match first
+func SearchContent(query string) ([]db.ContentMatch, error) {
+	filename, err := contentIndexFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := db.OpenContentIndex(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+
+	return index.Search(query)
+}
+
+// Highlights the matching terms in a snippet from the content index
+func FormatSnippet(snippet string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(snippet, db.ContentSnippetMatchStart) {
+		if i := strings.Index(part, db.ContentSnippetMatchEnd); i >= 0 {
+			b.WriteString(aurora.Bold(aurora.Green(part[:i])).String())
+			part = part[i+len(db.ContentSnippetMatchEnd):]
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
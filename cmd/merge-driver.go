@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/src-d/go-git.v4"
+
+	"github.com/DanNixon/voile/db"
+)
+
+const (
+	mergeDriverName    = "voile"
+	mergeDriverCommand = "voile merge-driver %O %A %B"
+)
+
+var mergeDriverCmd = &cobra.Command{
+	Use:   "merge-driver BASE OURS THEIRS",
+	Short: "Merge versions of a library for Git",
+	Long: `Merges two versions of a JSON library with their common ancestor bookmark by bookmark, writing the
+result to OURS. This is meant to be run by Git as a merge driver, so that merging branches that both
+changed the library does not produce conflicts in the JSON or bookmarks with the same number.
+
+Bookmarks added on both sides are all kept, those added in THEIRS are renumbered if their number is already
+used in OURS. Tags are merged, as are edits to different fields of a bookmark. If both sides changed the
+same field of a bookmark, or one deleted a bookmark the other changed, the most recent version is kept,
+the conflict is reported and the merge fails so the result can be checked before committing it.
+
+--install sets up the driver for the library in its Git repository, which is the same as adding
+  /bookmarks.json merge=voile
+to .gitattributes and running
+  git config merge.voile.driver "voile merge-driver %O %A %B"
+
+.gitattributes can be committed, but the Git config must be set in each clone.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if installFlag, _ := cmd.Flags().GetBool(InstallFlagName); installFlag {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(3)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		installFlag, _ := cmd.Flags().GetBool(InstallFlagName)
+		if installFlag {
+			err := installMergeDriver()
+			CheckError(err)
+			return
+		}
+
+		// Load all three versions of the library
+		var libraries [3]db.BookmarkLibrary
+		for i, filename := range args {
+			var err error
+			libraries[i], err = readLibraryForMerge(filename)
+			CheckError(err)
+		}
+
+		result := db.MergeLibraries(&libraries[0], &libraries[1], &libraries[2])
+
+		raw, err := db.FormatJSONLibrary(&result.Library)
+		CheckError(err)
+
+		err = ioutil.WriteFile(args[1], raw, 0644)
+		CheckError(err)
+
+		PrintMergeResult(&result, "ours", "theirs")
+
+		// Leave the library for Git to report as conflicted
+		if len(result.Conflicts) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// An empty file (e.g. no common ancestor) is an empty library
+func readLibraryForMerge(filename string) (db.BookmarkLibrary, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return db.BookmarkLibrary{}, err
+	}
+
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return db.BookmarkLibrary{}, nil
+	}
+
+	bmks, err := db.ParseJSONLibrary(raw)
+	if err != nil {
+		return bmks, errors.New(fmt.Sprintf("Could not read library %s: %s", filename, err))
+	}
+
+	return bmks, nil
+}
+
+func installMergeDriver() error {
+	if !IsBookmarksFileInGitRepository() {
+		return errors.New("Bookmarks file is not stored in a Git directory")
+	}
+	if _, ok := Store.(*db.JSONFileStore); !ok {
+		return errors.New("Only libraries stored as JSON can be merged")
+	}
+
+	gitDir := GetBookmarksFileParentDirectory()
+	file, err := filepath.Rel(gitDir, Store.(db.FileStore).Path())
+	if err != nil {
+		return err
+	}
+
+	// Use the driver for the library
+	attributesFile := filepath.Join(gitDir, ".gitattributes")
+	attribute := fmt.Sprintf("/%s merge=%s", filepath.ToSlash(file), mergeDriverName)
+
+	attributes, err := ioutil.ReadFile(attributesFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if !containsLine(string(attributes), attribute) {
+		if len(attributes) > 0 && !bytes.HasSuffix(attributes, []byte("\n")) {
+			attributes = append(attributes, '\n')
+		}
+		attributes = append(attributes, []byte(attribute+"\n")...)
+
+		err = ioutil.WriteFile(attributesFile, attributes, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added \"%s\" to %s\n", attribute, attributesFile)
+	}
+
+	// Define the driver
+	repo, err := git.PlainOpen(gitDir)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+
+	cfg.Raw.Section("merge").Subsection(mergeDriverName).
+		SetOption("name", "voile bookmark library").
+		SetOption("driver", mergeDriverCommand)
+
+	err = repo.Storer.SetConfig(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Set merge.%s.driver to \"%s\"\n", mergeDriverName, mergeDriverCommand)
+
+	return nil
+}
+
+func containsLine(s, line string) bool {
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(mergeDriverCmd)
+
+	mergeDriverCmd.Flags().Bool(InstallFlagName, false, "Set up the merge driver for the library in its Git repository")
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate FILE",
+	Short: "Copy the library to a new storage backend",
+	Long: `Copies every bookmark in the current library to a new library in FILE.
+The storage type is inferred from the file extension unless --storage is given.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Refuse to overwrite an existing library
+		if _, err := os.Stat(args[0]); err == nil {
+			fmt.Println("Destination file already exists")
+			os.Exit(1)
+		}
+
+		// Load bookmarks from file
+		bmks := ReadBookmarksFromFileReadOnly()
+
+		// Open destination store
+		storage, _ := cmd.Flags().GetString(StorageFlagName)
+		dest, err := db.NewStore(storage, args[0])
+		CheckError(err)
+
+		// Write bookmarks to destination
+		err = dest.Save(&bmks)
+		CheckError(err)
+
+		fmt.Printf("Migrated %d bookmarks to %s\n", bmks.Len(), args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().String(StorageFlagName, "", "Storage type of the new library (json or sqlite)")
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -11,13 +12,16 @@ import (
 var openCmd = &cobra.Command{
 	Use:   "open N",
 	Short: "Open a bookmark",
-	Long:  `Opens a bookmark identified by unique number N in a web browser.`,
+	Long: `Opens a bookmark identified by unique number N in a web browser.
+
+With --archived the latest snapshot saved by "voile archive" is opened instead.`,
+	Args: IsValidBookmarkNumberArgument,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Get bookmark number
 		bookmarkNumber, _ := strconv.Atoi(args[0])
 
 		// Load bookmarks from file
-		bmks := ReadBookmarksFromFile()
+		bmks := ReadBookmarksFromFileReadOnly()
 
 		// Get bookmark entry
 		bm, err := bmks.GetByNumber(bookmarkNumber)
@@ -26,6 +30,20 @@ var openCmd = &cobra.Command{
 		// Print bookmark to console
 		fmt.Println(FormatBookmark(bm, 0))
 
+		// Open local snapshot in browser
+		archivedFlag, _ := cmd.Flags().GetBool(ArchivedFlagName)
+		if archivedFlag {
+			snapshot := bm.LatestSnapshot()
+			if snapshot == nil {
+				CheckError(errors.New(fmt.Sprintf("Bookmark %d has not been archived", bm.Number)))
+			}
+
+			archive, err := GetArchive()
+			CheckError(err)
+			open.Run(archive.Path(snapshot.File))
+			return
+		}
+
 		// Open URL in browser
 		open.Run(bm.Url.String())
 	},
@@ -33,4 +51,6 @@ var openCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(openCmd)
+
+	openCmd.Flags().Bool(ArchivedFlagName, false, "Open the latest archived snapshot")
 }
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/gdamore/tcell/v2"
+	"github.com/skratchdot/open-golang/open"
+	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/tui"
+)
+
+const (
+	pickOpenAction   = "open"
+	pickCopyAction   = "copy"
+	pickEditAction   = "edit"
+	pickRetagAction  = "retag"
+	pickDeleteAction = "delete"
+)
+
+var pickActions = []tui.PickerAction{
+	{Key: tcell.KeyEnter, Name: pickOpenAction, Desc: "open"},
+	{Key: tcell.KeyCtrlY, Name: pickCopyAction, Desc: "copy"},
+	{Key: tcell.KeyCtrlE, Name: pickEditAction, Desc: "edit"},
+	{Key: tcell.KeyCtrlT, Name: pickRetagAction, Desc: "retag"},
+	{Key: tcell.KeyCtrlD, Name: pickDeleteAction, Desc: "delete"},
+}
+
+var pickCmd = &cobra.Command{
+	Use:     "pick [QUERY]",
+	Aliases: []string{"browse"},
+	Short:   "Interactively pick bookmarks",
+	Long: `Opens a full screen browser that filters the library as you type.
+Bookmarks can be selected with tab and then opened, copied, edited, retagged or deleted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		query := strings.Join(args, " ")
+
+		for {
+			// Load bookmarks from file
+			bmks := ReadBookmarksFromFileReadOnly()
+
+			// Pick bookmarks
+			result, err := tui.Pick(pickerItems(&bmks), pickActions, pickerFilter(&bmks), query)
+			CheckError(err)
+
+			// Keep filter when returning to the picker
+			query = result.Query
+
+			var numbers []int
+			for _, i := range result.Selected {
+				numbers = append(numbers, bmks.Bookmarks[i].Number)
+			}
+
+			switch result.Action {
+			case pickOpenAction:
+				for _, i := range result.Selected {
+					open.Run(bmks.Bookmarks[i].Url.String())
+				}
+				return
+			case pickCopyAction:
+				var urls []string
+				for _, i := range result.Selected {
+					urls = append(urls, bmks.Bookmarks[i].Url.String())
+				}
+				clipboard.WriteAll(strings.Join(urls, "\n"))
+				return
+			case pickEditAction, pickRetagAction, pickDeleteAction:
+				modifyPickedBookmarks(result.Action, numbers)
+			default:
+				return
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pickCmd)
+}
+
+func pickerItems(bmks *db.BookmarkLibrary) []tui.PickerItem {
+	var items []tui.PickerItem
+
+	for _, bm := range bmks.Bookmarks {
+		name := bm.Name
+		if !bm.HasName() {
+			name = "[untitled]"
+		}
+
+		preview := []string{
+			name,
+			"> " + bm.Url.String(),
+		}
+		if bm.Tags.Len() > 0 {
+			preview = append(preview, "# "+bm.Tags.String())
+		}
+		preview = append(preview, "+ "+bm.WhenAdded.Format(time.UnixDate))
+		if len(bm.Description) > 0 {
+			for _, l := range strings.Split(bm.Description, "\n") {
+				preview = append(preview, "? "+l)
+			}
+		}
+
+		items = append(items, tui.PickerItem{
+			Title:   fmt.Sprintf("%s  %s  %s", strconv.Itoa(bm.Number), name, bm.Url.String()),
+			Preview: preview,
+		})
+	}
+
+	return items
+}
+
+func pickerFilter(bmks *db.BookmarkLibrary) tui.PickerFilter {
+	// Map ranked bookmarks back to their position in the library
+	indices := make(map[int]int)
+	for i, bm := range bmks.Bookmarks {
+		indices[bm.Number] = i
+	}
+
+	return func(query string) []int {
+		var matches []int
+
+		terms := strings.Fields(query)
+		if len(terms) == 0 {
+			for i := range bmks.Bookmarks {
+				matches = append(matches, i)
+			}
+			return matches
+		}
+
+		for _, r := range bmks.Rank(terms) {
+			matches = append(matches, indices[r.Bookmark.Number])
+		}
+		return matches
+	}
+}
+
+func modifyPickedBookmarks(action string, numbers []int) {
+	// Reload with the lock held, the library may have changed while picking
+	bmks := ReadBookmarksFromFile()
+
+	bms := bookmarksByNumber(&bmks, numbers)
+	aliases := LoadTagAliases()
+
+	switch action {
+	case pickEditAction:
+		EditBookmarksInEditor(&bmks, bms, aliases)
+	case pickRetagAction:
+		for _, bm := range bms {
+			fmt.Println(FormatBookmark(bm, 0))
+		}
+
+		tags, err := tui.Prompt("Tags (comma separated, prefix with - to remove)")
+		CheckError(err)
+
+		for _, bm := range bms {
+			before := bm.Tags.String()
+			for _, t := range strings.Split(tags, ",") {
+				t = strings.TrimSpace(t)
+				if strings.HasPrefix(t, "-") {
+					bm.Tags.Remove(strings.TrimSpace(t[1:]))
+				} else {
+					bm.Tags.Append(strings.TrimPrefix(t, "+"))
+				}
+			}
+			aliases.Apply(&bm.Tags)
+
+			// Bookmarks that already had the tags are left as they were
+			if bm.Tags.String() != before {
+				bm.MarkUpdated()
+			}
+		}
+	case pickDeleteAction:
+		for _, bm := range bms {
+			fmt.Println(FormatBookmark(bm, 0))
+		}
+
+		rm, _ := tui.Confirm(fmt.Sprintf("Really remove %d bookmark(s)?", len(bms)))
+		if !rm {
+			fmt.Println("Bookmarks not removed.")
+			return
+		}
+
+		for _, n := range numbers {
+			err := bmks.DeleteByNumber(n)
+			CheckError(err)
+		}
+	}
+
+	// Save bookmarks back to file
+	SaveBookmarksToFile(&bmks)
+}
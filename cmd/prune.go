@@ -36,7 +36,7 @@ var pruneCmd = &cobra.Command{
 		result, err := tui.Option("Action", []tui.MultiChoiceOption{{"k", "keep"}, {"e", "edit"}, {"d", "delete"}})
 		CheckError(err)
 		if result == "e" {
-			EditBookmarkInEditor(&bmks, bm)
+			EditBookmarkInEditor(&bmks, bm, LoadTagAliases())
 			fmt.Println(FormatBookmark(bm, 0))
 		} else if result == "k" {
 			bm.MarkUpdated()
@@ -12,23 +12,26 @@ import (
 	"github.com/DanNixon/voile/db"
 )
 
-type FilteringOptions struct {
-	Cases []FilterCase
-}
-
-type FilterCase struct {
-	UserCares bool
-	Func      func(*db.Bookmark) bool
-}
-
 var rootCmd = &cobra.Command{
-	Use:   "voile",
+	Use:   "voile [QUERY]",
 	Short: "Query bookmark library",
-	Long:  `Query bookmark library and open results.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// Load bookmarks from file
-		bmks := ReadBookmarksFromFile()
+	Long: `Query bookmark library and open results.
+
+Bookmarks can be filtered by flags and/or a boolean query, e.g.:
+  voile 'tag:news AND (title:bbc OR url:*.co.uk) AND NOT tag:sport added:>2024-01-01'
+
+Query fields are tag, title, url, desc, added, updated and number.
+Terms without a field match the name, URL, description or any tag, as do
+terms with any other prefix such as a pasted URL. Globs given for url match
+either the whole URL or its host.
+
+With --rank the query is instead treated as free text and results are ordered
+by how well they match, tolerating typos.
 
+With --content the text of bookmarked pages indexed by "voile index" is
+searched, showing the passage that matched.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
 		// Get action flags
 		copyFlag, _ := cmd.Flags().GetBool(CopyFlagName)
 		openFlag, _ := cmd.Flags().GetBool(OpenFlagName)
@@ -36,38 +39,9 @@ var rootCmd = &cobra.Command{
 		// Get display flags
 		jsonFlag, _ := cmd.Flags().GetBool(JsonFlagName)
 
-		// Get filter options
-		bookmarkNumber, _ := cmd.Flags().GetInt(NumberFlagName)
-		tags, _ := cmd.Flags().GetStringSlice(TagsFlagName)
-		name, _ := cmd.Flags().GetString(NameFlagName)
-		url, _ := cmd.Flags().GetString(UrlFlagName)
-		desc, _ := cmd.Flags().GetString(DescFlagName)
-
-		// Setup filtering
-		d := FilteringOptions{
-			[]FilterCase{
-				{
-					cmd.Flags().Changed(NumberFlagName),
-					func(b *db.Bookmark) bool { return b.Number == bookmarkNumber },
-				},
-				{
-					cmd.Flags().Changed(TagsFlagName),
-					func(b *db.Bookmark) bool { return b.Tags.ContainsAllTags(tags) },
-				},
-				{
-					cmd.Flags().Changed(NameFlagName),
-					func(b *db.Bookmark) bool { return b.NameMatches(name) },
-				},
-				{
-					cmd.Flags().Changed(UrlFlagName),
-					func(b *db.Bookmark) bool { return b.UrlMatches(url) },
-				},
-				{
-					cmd.Flags().Changed(DescFlagName),
-					func(b *db.Bookmark) bool { return b.DescriptionMatches(desc) },
-				},
-			},
-		}
+		// Load and filter bookmarks
+		p := FilterParamsFromFlags(cmd, args)
+		results := QueryBookmarks(&p)
 
 		// Buffer for clipboard string
 		var clipboardBuffer bytes.Buffer
@@ -75,23 +49,16 @@ var rootCmd = &cobra.Command{
 		// Collect bookmarks for JSON output
 		var filteredBookmarks []db.Bookmark
 
-		// Filter bookmarks
-		i := 0
-		for _, bm := range bmks.Bookmarks {
-			// Check if this bookmark should be excluded from the results
-			if !includeBookmark(&d, &bm) {
-				continue
-			}
-
+		for i, bm := range results {
 			if jsonFlag {
 				// Add bookmark to filtered list for JSON output
-				filteredBookmarks = append(filteredBookmarks, bm)
+				filteredBookmarks = append(filteredBookmarks, *bm)
 			} else {
 				// Output to console in standard format
 				if i > 0 {
 					fmt.Println()
 				}
-				fmt.Println(FormatBookmark(&bm, i))
+				fmt.Println(FormatBookmarkWithSnippet(bm, i, p.Snippets[bm.Url.String()]))
 			}
 
 			// Buffer URLs for clipboard copy
@@ -106,8 +73,6 @@ var rootCmd = &cobra.Command{
 			if openFlag {
 				open.Run(bm.Url.String())
 			}
-
-			i++
 		}
 
 		// Print bookmark to console
@@ -130,24 +95,10 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.Flags().IntP(NumberFlagName, NumberFlagShort, 0, "Get bookmark by number")
-	rootCmd.Flags().StringSliceP(TagsFlagName, TagsFlagShort, []string{}, "Get bookmarks by tags")
-	rootCmd.Flags().StringP(NameFlagName, "s", "", "Search in name")
-	rootCmd.Flags().StringP(UrlFlagName, UrlFlagShort, "", "Search in URL")
-	rootCmd.Flags().StringP(DescFlagName, DescFlagShort, "", "Search in description")
+	AddFilteringFlags(rootCmd)
 
 	rootCmd.Flags().BoolP(OpenFlagName, OpenFlagShort, false, "Open bookmarks in browser")
 	rootCmd.Flags().BoolP(CopyFlagName, CopyFlagShort, false, "Copy bookmark URLs to clipboard")
 
 	rootCmd.Flags().BoolP(JsonFlagName, JsonFlagShort, false, "Output in JSON format")
 }
-
-func includeBookmark(query *FilteringOptions, bm *db.Bookmark) bool {
-	res := true
-	for _, q := range query.Cases {
-		if q.UserCares {
-			res = res && q.Func(bm)
-		}
-	}
-	return res
-}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/server"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the library over HTTP",
+	Long: `Serves a web interface for searching, browsing by tag, adding, editing and deleting bookmarks.
+
+The search box takes the same queries as the root command, and the number, tag, name, url, desc, content
+and rank query parameters filter as the flags of the same name do.
+
+A JSON API is served below /api/v1/ when VOILE_API_TOKEN is set, requests must send the token in an
+"Authorization: Bearer TOKEN" header:
+  GET    /api/v1/bookmarks      bookmarks matching the same query parameters as the web interface
+  POST   /api/v1/bookmarks      add a bookmark
+  GET    /api/v1/bookmarks/N    get a bookmark
+  PUT    /api/v1/bookmarks/N    replace the title, uri, description and tags of a bookmark
+  PATCH  /api/v1/bookmarks/N    change only the fields given
+  DELETE /api/v1/bookmarks/N    delete a bookmark
+  GET    /api/v1/tags           every tag with how many bookmarks have it
+
+  POST   /api/v1/save           save a page from a browser, see below
+
+Bookmarks are returned with an ETag, send it in an If-Match header to only change a bookmark if nobody
+else has since.
+
+Pages are saved from a browser with the bookmarklet at /bookmarklet, or by extensions posting the url,
+title, description and tags (comma separated in a form, or a list in JSON) of a page to /api/v1/save.
+The bookmarklet asks for VOILE_API_TOKEN once, before it is shown, and can only save pages once it has.
+Saved pages are added the same way as "voile add", fetching the title if none is given and with
+--metadata and --archive behaving as they do for "voile add".
+
+Changes are saved and committed to Git the same way as any other command. By default the server is only
+reachable from this machine.
+
+Requests are refused unless sent to the host name of --address (or localhost and its addresses when
+listening on localhost), add any other names the server is reached by with --host. When listening on any
+address other than localhost VOILE_API_TOKEN must be set, and the web interface asks for it before showing
+the library.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		address, _ := cmd.Flags().GetString(AddressFlagName)
+		metadataFlag, _ := cmd.Flags().GetBool(MetadataFlagName)
+		archiveFlag, _ := cmd.Flags().GetBool(ArchiveFlagName)
+		hosts, _ := cmd.Flags().GetStringSlice(HostFlagName)
+
+		s := server.NewServer(Store)
+		s.Filter = filterValues
+		s.Token = viper.GetString(ApiTokenConfigEntry)
+
+		var err error
+		s.AllowedHosts, s.RequireLogin, err = serverHosts(address, hosts)
+		CheckError(err)
+		if s.RequireLogin && len(s.Token) == 0 {
+			CheckError(errors.New("VOILE_API_TOKEN must be set to serve on an address other than localhost"))
+		}
+
+		s.Prepare = func(bm *db.Bookmark) error {
+			aliases, err := ReadTagAliases()
+			if err != nil {
+				return err
+			}
+
+			aliases.Apply(&bm.Tags)
+			return nil
+		}
+		s.Fetch = func(page server.SavedPage) (server.AddFunc, error) {
+			nb := NewBookmark{
+				Url:           page.Url,
+				Name:          page.Title,
+				Description:   page.Description,
+				Tags:          page.Tags,
+				FetchTitle:    len(page.Title) == 0,
+				FetchMetadata: metadataFlag,
+				Archive:       archiveFlag,
+			}
+
+			fetched, err := FetchNewBookmark(nb)
+			if err != nil {
+				return nil, err
+			}
+
+			// Tag aliases are applied by Prepare
+			return func(bmks *db.BookmarkLibrary, page server.SavedPage) (*db.Bookmark, error) {
+				bm, err := AddBookmark(bmks, nb, &fetched, db.TagAliases{})
+				for _, w := range fetched.Warnings {
+					log.Printf("Saving %s: %s", nb.Url, w)
+				}
+				return bm, err
+			}, nil
+		}
+		s.Saved = CommitChangesToBookmarkFile
+
+		fmt.Printf("Serving on http://%s\n", address)
+		CheckError(http.ListenAndServe(address, s))
+	},
+}
+
+// Returns the hosts requests may be sent to, and whether the address is
+// reachable from other machines
+func serverHosts(address string, extra []string) ([]string, bool, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ip := net.ParseIP(host)
+	anyHost := len(host) == 0 || (ip != nil && ip.IsUnspecified())
+	public := true
+
+	var hosts []string
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		public = false
+		for _, h := range []string{"localhost", "127.0.0.1", "::1"} {
+			hosts = append(hosts, net.JoinHostPort(h, port))
+		}
+	} else if !anyHost {
+		hosts = append(hosts, net.JoinHostPort(host, port))
+	}
+
+	for _, h := range extra {
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(h, port)
+		}
+		hosts = append(hosts, h)
+	}
+
+	// Listening on every address, any host name is allowed unless given
+	if anyHost && len(extra) == 0 {
+		return nil, true, nil
+	}
+
+	return hosts, public, nil
+}
+
+// Filters bookmarks with the root command semantics
+func filterValues(values url.Values, bmks *db.BookmarkLibrary) ([]*db.Bookmark, error) {
+	p, err := FilterParamsFromValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterBookmarks(&p, bmks)
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String(AddressFlagName, "localhost:8080", "Address to listen on")
+	serveCmd.Flags().StringSlice(HostFlagName, []string{}, "Other host names the server is reached by")
+	serveCmd.Flags().BoolP(MetadataFlagName, MetadataFlagShort, false, "Get description and canonical URL of saved pages from page metadata")
+	serveCmd.Flags().Bool(ArchiveFlagName, false, "Save a snapshot of saved pages")
+}
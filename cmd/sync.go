@@ -0,0 +1,452 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+
+	"github.com/DanNixon/voile/db"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Pull and push changes to the library",
+	Long: `Commits any changes to the library, then pulls and pushes the current branch of the Git repository
+containing it.
+
+If both this and the remote repository have changed since they were last synced the two versions of the
+library are merged bookmark by bookmark, so adding, tagging or deleting bookmarks on several machines never
+conflicts. Remote bookmarks keep their numbers, bookmarks added locally are renumbered if their number was
+taken. Where the same field of a bookmark was changed on both machines the most recent change is kept and
+the conflict reported.
+
+Changes to tag aliases and page snapshots are merged too, sync stops without changing anything if any
+other file was changed on both machines.
+
+SSH remotes are authenticated with the SSH agent.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		remote, _ := cmd.Flags().GetString(RemoteFlagName)
+
+		if !IsBookmarksFileInGitRepository() {
+			fmt.Println("Bookmarks file is not stored in a Git directory")
+			return
+		}
+
+		// Nothing else may change the library while syncing
+		err := Store.Lock()
+		CheckError(err)
+		defer Store.Unlock()
+
+		err = CommitChangesToBookmarkFile()
+		CheckError(err)
+
+		err = syncRepository(remote)
+		CheckError(err)
+	},
+}
+
+func syncRepository(remote string) error {
+	repo, err := git.PlainOpen(GetBookmarksFileParentDirectory())
+	if err != nil {
+		return err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	// Merging replaces files in the worktree
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+	for file, s := range status {
+		if s.Worktree != git.Untracked && (s.Worktree != git.Unmodified || s.Staging != git.Unmodified) {
+			return errors.New(fmt.Sprintf("Uncommitted changes to %s, commit or discard them before syncing", file))
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return errors.New("HEAD is not a branch")
+	}
+
+	err = unpackRemoteReferences(repo, remote)
+	if err != nil {
+		return err
+	}
+
+	// Pull
+	fmt.Printf("Fetching from %s\n", remote)
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: remote,
+	})
+	if err == transport.ErrEmptyRemoteRepository {
+		return pushBranch(repo, remote, head.Name())
+	}
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, head.Name().Short()), true)
+	if err == plumbing.ErrReferenceNotFound {
+		return pushBranch(repo, remote, head.Name())
+	}
+	if err != nil {
+		return err
+	}
+
+	local, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	theirs, err := repo.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return err
+	}
+
+	if local.Hash == theirs.Hash {
+		fmt.Println("Already up to date")
+		return nil
+	}
+
+	behind, err := local.IsAncestor(theirs)
+	if err != nil {
+		return err
+	}
+	if behind {
+		fmt.Printf("Updating to %s\n", theirs.Hash)
+		return wt.Reset(&git.ResetOptions{
+			Commit: theirs.Hash,
+			Mode:   git.HardReset,
+		})
+	}
+
+	ahead, err := theirs.IsAncestor(local)
+	if err != nil {
+		return err
+	}
+	if !ahead {
+		err = mergeCommits(repo, wt, local, theirs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return pushBranch(repo, remote, head.Name())
+}
+
+// go-git fails to update references only stored in packed-refs, as written
+// by "git clone" or "git gc", so store those of the remote as loose files
+func unpackRemoteReferences(repo *git.Repository, remote string) error {
+	refs, err := repo.References()
+	if err != nil {
+		return err
+	}
+
+	prefix := plumbing.NewRemoteReferenceName(remote, "").String()
+	return refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || !strings.HasPrefix(ref.Name().String(), prefix) {
+			return nil
+		}
+
+		return repo.Storer.SetReference(ref)
+	})
+}
+
+func pushBranch(repo *git.Repository, remote string, branch plumbing.ReferenceName) error {
+	fmt.Printf("Pushing to %s\n", remote)
+	err := repo.Push(&git.PushOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(branch + ":" + branch)},
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	return nil
+}
+
+// Merges the remote commit into the local one, merging the library and tag
+// aliases and taking other files from whichever side changed them
+func mergeCommits(repo *git.Repository, wt *git.Worktree, local, theirs *object.Commit) error {
+	bases, err := local.MergeBase(theirs)
+	if err != nil {
+		return err
+	}
+	if len(bases) == 0 {
+		return errors.New("Local and remote histories have nothing in common")
+	}
+	base := bases[0]
+
+	fmt.Printf("Merging %s\n", theirs.Hash)
+
+	// The library can only be merged if stored as JSON
+	fs := Store.(db.FileStore)
+	if _, ok := Store.(*db.JSONFileStore); !ok {
+		return errors.New("Only libraries stored as JSON can be merged, pull and push with \"voile git\" instead")
+	}
+
+	gitDir := GetBookmarksFileParentDirectory()
+	libraryFile, err := filepath.Rel(gitDir, fs.Path())
+	if err != nil {
+		return err
+	}
+	aliasesFile, err := filepath.Rel(gitDir, db.TagAliasesForFile(fs.Path()))
+	if err != nil {
+		return err
+	}
+	libraryFile = filepath.ToSlash(libraryFile)
+	aliasesFile = filepath.ToSlash(aliasesFile)
+
+	// Contents of the merged commit that differ from the remote commit, nil
+	// for deleted files
+	files := make(map[string][]byte)
+
+	// Merge the library, keeping the numbers of remote bookmarks as they may
+	// have been seen elsewhere already
+	var libraries [3]db.BookmarkLibrary
+	for i, c := range []*object.Commit{base, theirs, local} {
+		raw, err := readCommitFile(c, libraryFile)
+		if err != nil {
+			return err
+		}
+
+		if raw != nil {
+			libraries[i], err = db.ParseJSONLibrary(raw)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Could not read library in %s: %s", c.Hash, err))
+			}
+		}
+	}
+
+	merged := db.MergeLibraries(&libraries[0], &libraries[1], &libraries[2])
+	files[libraryFile], err = db.FormatJSONLibrary(&merged.Library)
+	if err != nil {
+		return err
+	}
+
+	// Take any other files changed only locally
+	localChanges, err := changedFiles(base, local)
+	if err != nil {
+		return err
+	}
+	remoteChanges, err := changedFiles(base, theirs)
+	if err != nil {
+		return err
+	}
+
+	for file := range localChanges {
+		if file == libraryFile {
+			continue
+		}
+
+		localRaw, err := readCommitFile(local, file)
+		if err != nil {
+			return err
+		}
+
+		if file == aliasesFile {
+			var aliases [3]db.TagAliases
+			for i, c := range []*object.Commit{base, local, theirs} {
+				aliases[i], err = readCommitTagAliases(c, file)
+				if err != nil {
+					return err
+				}
+			}
+
+			files[file], err = json.MarshalIndent(db.MergeTagAliases(aliases[0], aliases[1], aliases[2]), "", "  ")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if remoteChanges[file] {
+			remoteRaw, err := readCommitFile(theirs, file)
+			if err != nil {
+				return err
+			}
+
+			if !bytes.Equal(localRaw, remoteRaw) {
+				return errors.New(fmt.Sprintf("%s was changed both locally and remotely, merge with \"voile git\" instead", file))
+			}
+			continue
+		}
+
+		files[file] = localRaw
+	}
+
+	// go-git keeps no reflog, so the local commits would be lost if the branch
+	// were left at the remote commit
+	err = commitMerge(wt, gitDir, files, local, theirs)
+	if err != nil {
+		resetErr := wt.Reset(&git.ResetOptions{
+			Commit: local.Hash,
+			Mode:   git.HardReset,
+		})
+		if resetErr != nil {
+			return errors.New(fmt.Sprintf("%s, and could not go back to local commit %s: %s", err, local.Hash, resetErr))
+		}
+		return err
+	}
+
+	PrintMergeResult(&merged, "remote", "local")
+
+	return nil
+}
+
+// Commits the merged files on top of the remote commit, which moves the branch
+func commitMerge(wt *git.Worktree, gitDir string, files map[string][]byte, local, theirs *object.Commit) error {
+	// Start from the remote commit and apply the merged changes
+	err := wt.Reset(&git.ResetOptions{
+		Commit: theirs.Hash,
+		Mode:   git.HardReset,
+	})
+	if err != nil {
+		return err
+	}
+
+	for file, raw := range files {
+		if raw == nil {
+			_, err = wt.Remove(file)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		filename := filepath.Join(gitDir, filepath.FromSlash(file))
+		err = os.MkdirAll(filepath.Dir(filename), 0755)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(filename, raw, 0644)
+		if err != nil {
+			return err
+		}
+
+		_, err = wt.Add(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = wt.Commit(fmt.Sprintf("voile sync merge of %s", theirs.Hash), &git.CommitOptions{
+		Author:  commitSignature(),
+		Parents: []plumbing.Hash{local.Hash, theirs.Hash},
+	})
+	return err
+}
+
+// Reports bookmarks that were renumbered or changed on both sides of a merge
+func PrintMergeResult(result *db.LibraryMergeResult, oursName, theirsName string) {
+	var renumbered []int
+	for from := range result.Renumbered {
+		renumbered = append(renumbered, from)
+	}
+	sort.Ints(renumbered)
+	for _, from := range renumbered {
+		fmt.Printf("Bookmark %d added in %s is now bookmark %d\n", from, theirsName, result.Renumbered[from])
+	}
+
+	for _, c := range result.Conflicts {
+		if c.Field == "deleted" {
+			deletedIn, changedIn := oursName, theirsName
+			if c.Theirs == "deleted" {
+				deletedIn, changedIn = theirsName, oursName
+			}
+			fmt.Printf("Bookmark %d (%s) was deleted in %s but changed in %s, it has been kept\n", c.Number, c.Url, deletedIn, changedIn)
+		} else if c.Field == "duplicate" {
+			fmt.Printf("Bookmark %s was given the URL of bookmark %d (%s), they have been merged\n", c.Theirs, c.Number, c.Url)
+		} else {
+			fmt.Printf("Bookmark %d (%s) %s changed in both %s (%q) and %s (%q), kept the most recent\n",
+				c.Number, c.Url, c.Field, oursName, c.Ours, theirsName, c.Theirs)
+		}
+	}
+}
+
+// Paths of files that differ between two commits
+func changedFiles(from, to *object.Commit) (map[string]bool, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]bool)
+	for _, c := range changes {
+		file := c.To.Name
+		if len(file) == 0 {
+			file = c.From.Name
+		}
+		files[file] = true
+	}
+
+	return files, nil
+}
+
+// Returns nil contents if the file does not exist in the commit
+func readCommitFile(c *object.Commit, file string) ([]byte, error) {
+	f, err := c.File(file)
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(contents), nil
+}
+
+func readCommitTagAliases(c *object.Commit, file string) (db.TagAliases, error) {
+	aliases := make(db.TagAliases)
+
+	raw, err := readCommitFile(c, file)
+	if err != nil || raw == nil {
+		return aliases, err
+	}
+
+	err = json.Unmarshal(raw, &aliases)
+	return aliases, err
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().String(RemoteFlagName, "origin", "Remote to pull from and push to")
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+
+	"github.com/DanNixon/voile/db"
+)
+
+// Creates a bare remote with a library of one bookmark pushed to it
+func createSyncTestRemote(t *testing.T, dir string) string {
+	// go-git pushes to and fetches from remotes on disk with the Git binaries
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("Git is not installed")
+	}
+
+	remote := filepath.Join(dir, "remote.git")
+	_, err := git.PlainInit(remote, true)
+	require.NoError(t, err)
+
+	first := filepath.Join(dir, "first")
+	repo, err := git.PlainInit(first, false)
+	require.NoError(t, err)
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}})
+	require.NoError(t, err)
+
+	addSyncTestBookmark(t, first, "https://github.com")
+	require.NoError(t, syncRepository("origin"))
+
+	return remote
+}
+
+func cloneSyncTestRemote(t *testing.T, remote, dir string) {
+	_, err := git.PlainClone(dir, false, &git.CloneOptions{URL: remote})
+	require.NoError(t, err)
+}
+
+// Points the commands at the library of a clone
+func useSyncTestLibrary(dir string) {
+	Store = db.NewJSONFileStore(filepath.Join(dir, "bookmarks.json"))
+}
+
+// Adds and commits a bookmark as "voile add" would
+func addSyncTestBookmark(t *testing.T, dir, url string) {
+	useSyncTestLibrary(dir)
+
+	bmks, err := Store.Load()
+	require.NoError(t, err)
+
+	bm := bmks.NewEntry()
+	require.NoError(t, bm.Url.Parse(url))
+
+	require.NoError(t, Store.Save(&bmks))
+	require.NoError(t, CommitChangesToBookmarkFile())
+}
+
+func commitSyncTestFile(t *testing.T, dir, file, contents string) {
+	filename := filepath.Join(dir, filepath.FromSlash(file))
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	require.NoError(t, ioutil.WriteFile(filename, []byte(contents), 0644))
+
+	repo, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	_, err = wt.Add(file)
+	require.NoError(t, err)
+	_, err = wt.Commit("Add "+file, &git.CommitOptions{Author: commitSignature()})
+	require.NoError(t, err)
+}
+
+func syncTestHead(t *testing.T, dir string) plumbing.Hash {
+	repo, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	return head.Hash()
+}
+
+func syncTestUrls(t *testing.T, dir string) []string {
+	useSyncTestLibrary(dir)
+
+	bmks, err := Store.Load()
+	require.NoError(t, err)
+
+	var urls []string
+	for _, bm := range bmks.Bookmarks {
+		urls = append(urls, bm.Url.String())
+	}
+	return urls
+}
+
+func syncTest(dir string) error {
+	useSyncTestLibrary(dir)
+	return syncRepository("origin")
+}
+
+func TestSyncFastForward(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+	defer func(s db.Store) { Store = s }(Store)
+
+	remote := createSyncTestRemote(t, dir)
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	cloneSyncTestRemote(t, remote, a)
+	cloneSyncTestRemote(t, remote, b)
+
+	// Pushed when only changed locally
+	addSyncTestBookmark(t, a, "https://example.com")
+	require.NoError(t, syncTest(a))
+
+	// Pulled when only changed remotely
+	require.NoError(t, syncTest(b))
+	assert.Equal(t, syncTestHead(t, a), syncTestHead(t, b))
+	assert.Equal(t, []string{"https://github.com", "https://example.com"}, syncTestUrls(t, b))
+
+	require.NoError(t, syncTest(b))
+	assert.Equal(t, syncTestHead(t, a), syncTestHead(t, b))
+}
+
+func TestSyncMerge(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+	defer func(s db.Store) { Store = s }(Store)
+
+	remote := createSyncTestRemote(t, dir)
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	cloneSyncTestRemote(t, remote, a)
+	cloneSyncTestRemote(t, remote, b)
+
+	addSyncTestBookmark(t, a, "https://example.com/a")
+	commitSyncTestFile(t, a, "notes.txt", "a")
+	require.NoError(t, syncTest(a))
+	remoteHead := syncTestHead(t, a)
+
+	addSyncTestBookmark(t, b, "https://example.com/b")
+	localHead := syncTestHead(t, b)
+	require.NoError(t, syncTest(b))
+
+	// Remote bookmarks keep their numbers
+	assert.Equal(t, []string{"https://github.com", "https://example.com/a", "https://example.com/b"}, syncTestUrls(t, b))
+	notes, err := ioutil.ReadFile(filepath.Join(b, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(notes))
+
+	repo, err := git.PlainOpen(b)
+	require.NoError(t, err)
+	merge, err := repo.CommitObject(syncTestHead(t, b))
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{localHead, remoteHead}, merge.ParentHashes)
+
+	// The merge was pushed
+	require.NoError(t, syncTest(a))
+	assert.Equal(t, merge.Hash, syncTestHead(t, a))
+	assert.Equal(t, syncTestUrls(t, b), syncTestUrls(t, a))
+}
+
+func TestSyncMergeFailureKeepsLocalCommits(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+	defer func(s db.Store) { Store = s }(Store)
+
+	remote := createSyncTestRemote(t, dir)
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	cloneSyncTestRemote(t, remote, a)
+	cloneSyncTestRemote(t, remote, b)
+
+	commitSyncTestFile(t, a, "notes", "a")
+	require.NoError(t, syncTest(a))
+
+	// Only fails once the worktree is reset to the remote commit, as notes
+	// is then a file
+	addSyncTestBookmark(t, b, "https://example.com/b")
+	commitSyncTestFile(t, b, "notes/b.txt", "b")
+	localHead := syncTestHead(t, b)
+	assert.Error(t, syncTest(b))
+
+	assert.Equal(t, localHead, syncTestHead(t, b))
+	assert.Equal(t, []string{"https://github.com", "https://example.com/b"}, syncTestUrls(t, b))
+	notes, err := ioutil.ReadFile(filepath.Join(b, "notes", "b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(notes))
+}
+
+func TestSyncBothChanged(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+	defer func(s db.Store) { Store = s }(Store)
+
+	remote := createSyncTestRemote(t, dir)
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	cloneSyncTestRemote(t, remote, a)
+	cloneSyncTestRemote(t, remote, b)
+
+	commitSyncTestFile(t, a, "notes.txt", "a")
+	require.NoError(t, syncTest(a))
+
+	commitSyncTestFile(t, b, "notes.txt", "b")
+	localHead := syncTestHead(t, b)
+	err := syncTest(b)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "notes.txt was changed both locally and remotely")
+	assert.Equal(t, localHead, syncTestHead(t, b))
+}
@@ -1,33 +1,236 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/logrusorgru/aurora"
 	"github.com/spf13/cobra"
+
+	"github.com/DanNixon/voile/db"
 )
 
-var tagFormatStr = fmt.Sprintf("- %s (%s)",
+var tagFormatStr = fmt.Sprintf("%%s- %s (%s)",
 	aurora.Blue("%s"), aurora.Cyan("%d"))
 
 var tagsCmd = &cobra.Command{
 	Use:   "tags",
-	Short: "List all tags",
-	Long:  `Lists all tags used in the library.`,
+	Short: "List and manage tags",
+	Long: `Lists all tags used in the library.
+
+Tags containing "/" form a hierarchy (e.g. lang/go and lang/rust are below lang) and are listed
+as a tree. Counts include bookmarks tagged with any tag below.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Load bookmarks from file
-		bmks := ReadBookmarksFromFile()
+		bmks := ReadBookmarksFromFileReadOnly()
 
 		// Get tags
 		tags := bmks.GetAllTags()
 
 		// Print tags
-		for _, tag := range tags.Tags.Tags {
-			fmt.Println(fmt.Sprintf(tagFormatStr, tag, tags.Count[tag]))
+		printTagTree(tags.Tree(), &tags, "")
+	},
+}
+
+var tagsRenameCmd = &cobra.Command{
+	Use:   "rename OLD NEW",
+	Short: "Rename a tag",
+	Long:  `Renames a tag on every bookmark, along with any tags below it in the hierarchy.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		bmks := ReadBookmarksFromFile()
+		aliases := LoadTagAliases()
+
+		changed := renameTag(&bmks, aliases, args[0], args[1])
+		fmt.Printf("Renamed %s to %s on %d bookmarks\n", aurora.Blue(args[0]), aurora.Blue(args[1]), changed)
+
+		SaveBookmarksAndTagAliasesToFile(&bmks, aliases)
+	},
+}
+
+var tagsMergeCmd = &cobra.Command{
+	Use:   "merge TAG... INTO TARGET",
+	Short: "Merge tags into one",
+	Long:  `Replaces each of the tags (and any tags below them in the hierarchy) with the target tag.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 3 || args[len(args)-2] != "INTO" {
+			return errors.New("Expected one or more tags followed by INTO and the target tag")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		target := args[len(args)-1]
+
+		bmks := ReadBookmarksFromFile()
+		aliases := LoadTagAliases()
+
+		for _, tag := range args[:len(args)-2] {
+			changed := renameTag(&bmks, aliases, tag, target)
+			fmt.Printf("Merged %s into %s on %d bookmarks\n", aurora.Blue(tag), aurora.Blue(target), changed)
 		}
+
+		SaveBookmarksAndTagAliasesToFile(&bmks, aliases)
+	},
+}
+
+var tagsDeleteCmd = &cobra.Command{
+	Use:   "delete TAG",
+	Short: "Delete a tag",
+	Long:  `Removes a tag, along with any tags below it in the hierarchy, from every bookmark.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tag := args[0]
+
+		bmks := ReadBookmarksFromFile()
+		aliases := LoadTagAliases()
+
+		changed := bmks.DeleteTag(tag)
+		fmt.Printf("Deleted %s from %d bookmarks\n", aurora.Blue(tag), changed)
+
+		// Aliases to the tag no longer make sense
+		for alias, target := range aliases {
+			if db.IsTagOrDescendant(target, tag) {
+				delete(aliases, alias)
+			}
+		}
+
+		SaveBookmarksAndTagAliasesToFile(&bmks, aliases)
+	},
+}
+
+var tagsAliasCmd = &cobra.Command{
+	Use:   "alias [ALIAS TAG]",
+	Short: "List or add tag aliases",
+	Long: `Without arguments lists tag aliases, otherwise makes ALIAS an alias of TAG.
+
+Aliased tags are replaced when bookmarks are added or edited. Adding an alias also replaces it on
+existing bookmarks.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return errors.New("Expected no arguments or an alias and a tag")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// List aliases
+		if len(args) == 0 {
+			aliases := LoadTagAliases()
+
+			var names db.TagList
+			for alias := range aliases {
+				names.Append(alias)
+			}
+			for _, alias := range names.Tags {
+				fmt.Printf("%s %s %s\n", aurora.Blue(alias), aurora.Red("->"), aurora.Blue(aliases[alias]))
+			}
+			return
+		}
+
+		alias, tag := args[0], args[1]
+
+		bmks := ReadBookmarksFromFile()
+		aliases := LoadTagAliases()
+
+		if db.IsTagOrDescendant(aliases.Resolve(tag), alias) {
+			CheckError(errors.New(fmt.Sprintf("Aliasing %s to %s would form a loop", alias, tag)))
+		}
+		aliases[alias] = tag
+
+		changed := bmks.RenameTag(alias, tag)
+		fmt.Printf("Replaced %s with %s on %d bookmarks\n", aurora.Blue(alias), aurora.Blue(tag), changed)
+
+		SaveBookmarksAndTagAliasesToFile(&bmks, aliases)
+	},
+}
+
+var tagsUnaliasCmd = &cobra.Command{
+	Use:   "unalias ALIAS",
+	Short: "Remove a tag alias",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		bmks := ReadBookmarksFromFile()
+		aliases := LoadTagAliases()
+
+		if _, ok := aliases[args[0]]; !ok {
+			CheckError(errors.New(fmt.Sprintf("No alias %s found", args[0])))
+		}
+		delete(aliases, args[0])
+
+		SaveBookmarksAndTagAliasesToFile(&bmks, aliases)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(tagsCmd)
+
+	tagsCmd.AddCommand(tagsRenameCmd)
+	tagsCmd.AddCommand(tagsMergeCmd)
+	tagsCmd.AddCommand(tagsDeleteCmd)
+	tagsCmd.AddCommand(tagsAliasCmd)
+	tagsCmd.AddCommand(tagsUnaliasCmd)
+}
+
+// Renames a tag on bookmarks and in aliases that point to it
+func renameTag(bmks *db.BookmarkLibrary, aliases db.TagAliases, from, to string) int {
+	for alias, target := range aliases {
+		if renamed, ok := db.RenameTag(target, from, to); ok {
+			aliases[alias] = renamed
+		}
+	}
+
+	return bmks.RenameTag(from, to)
+}
+
+func tagAliasesFilename() string {
+	fs, ok := Store.(db.FileStore)
+	if !ok {
+		return ""
+	}
+
+	return db.TagAliasesForFile(fs.Path())
+}
+
+// Loads the tag aliases of the library, of which there are none unless it is
+// stored in a file
+func ReadTagAliases() (db.TagAliases, error) {
+	filename := tagAliasesFilename()
+	if len(filename) == 0 {
+		return make(db.TagAliases), nil
+	}
+
+	return db.LoadTagAliases(filename)
+}
+
+func LoadTagAliases() db.TagAliases {
+	aliases, err := ReadTagAliases()
+	CheckError(err)
+
+	return aliases
+}
+
+func SaveTagAliases(aliases db.TagAliases) {
+	filename := tagAliasesFilename()
+	if len(filename) == 0 {
+		if len(aliases) > 0 {
+			CheckError(errors.New("Tag aliases require a library stored in a file"))
+		}
+		return
+	}
+
+	// Avoid creating a file when there have never been any aliases
+	if _, err := os.Stat(filename); os.IsNotExist(err) && len(aliases) == 0 {
+		return
+	}
+
+	err := aliases.Save(filename)
+	CheckError(err)
+}
+
+func printTagTree(nodes []db.TagTreeNode, tags *db.AllTags, indent string) {
+	for _, n := range nodes {
+		fmt.Println(fmt.Sprintf(tagFormatStr, indent, n.Name(), tags.Total[n.Tag]))
+		printTagTree(n.Children, tags, indent+"  ")
+	}
 }
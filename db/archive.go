@@ -0,0 +1,60 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const ArchiveDirectoryName = "archive"
+
+// Saved copy of a bookmarked page
+type Snapshot struct {
+	Taken time.Time `json:"taken"`
+
+	// Path of the snapshot relative to the archive directory
+	File string `json:"file"`
+}
+
+// Content addressed store of page snapshots
+type Archive struct {
+	Dir string
+}
+
+// Archive kept in the same directory as a library file
+func ArchiveForFile(filename string) Archive {
+	return Archive{
+		Dir: filepath.Join(filepath.Dir(filename), ArchiveDirectoryName),
+	}
+}
+
+// Stores data named by its hash, so identical snapshots share a single file
+func (a *Archive) Put(data []byte, ext string) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	file := path.Join(hash[:2], hash+ext)
+
+	filename := a.Path(file)
+	if _, err := os.Stat(filename); err == nil {
+		return file, nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeFileAtomic(filename, data, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return file, nil
+}
+
+func (a *Archive) Path(file string) string {
+	return filepath.Join(a.Dir, filepath.FromSlash(file))
+}
@@ -0,0 +1,61 @@
+package db_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func TestArchiveForFile(t *testing.T) {
+	a := db.ArchiveForFile(filepath.Join("library", "bookmarks.json"))
+	assert.Equal(t, filepath.Join("library", "archive"), a.Dir)
+}
+
+func TestArchivePut(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	a := db.Archive{Dir: dir}
+
+	file, err := a.Put([]byte("<html></html>"), ".html")
+	assert.Nil(t, err)
+	assert.Regexp(t, "^[0-9a-f]{2}/[0-9a-f]{64}\\.html$", file)
+
+	data, err := ioutil.ReadFile(a.Path(file))
+	assert.Nil(t, err)
+	assert.Equal(t, "<html></html>", string(data))
+
+	// Same content is stored once
+	again, err := a.Put([]byte("<html></html>"), ".html")
+	assert.Nil(t, err)
+	assert.Equal(t, file, again)
+
+	other, err := a.Put([]byte("<html>changed</html>"), ".html")
+	assert.Nil(t, err)
+	assert.NotEqual(t, file, other)
+}
+
+func TestBookmarkAddSnapshot(t *testing.T) {
+	var bm db.Bookmark
+	assert.Nil(t, bm.LatestSnapshot())
+
+	first := time.Date(2020, time.March, 4, 12, 0, 0, 0, time.UTC)
+	bm.AddSnapshot(db.Snapshot{Taken: first, File: "ab/abc.html"})
+	assert.Equal(t, "ab/abc.html", bm.LatestSnapshot().File)
+
+	// Unchanged page only updates the time
+	second := first.Add(time.Hour)
+	bm.AddSnapshot(db.Snapshot{Taken: second, File: "ab/abc.html"})
+	assert.Equal(t, 1, len(bm.Snapshots))
+	assert.Equal(t, second, bm.LatestSnapshot().Taken)
+
+	bm.AddSnapshot(db.Snapshot{Taken: second, File: "cd/cde.html"})
+	assert.Equal(t, 2, len(bm.Snapshots))
+	assert.Equal(t, "cd/cde.html", bm.LatestSnapshot().File)
+}
@@ -11,8 +11,9 @@ import (
 	"golang.org/x/text/search"
 )
 
-const BookmarkInteractiveFileFormatString = `# Bookmark
-# Headings must not be edited
+const BookmarkInteractiveFileFormatString = BookmarkInteractiveFileSectionHeader + "\n" + bookmarkInteractiveFileFieldsFormatString
+
+const bookmarkInteractiveFileFieldsFormatString = `# Headings must not be edited
 # Lines starting with a # are ignored
 ## Title
 %s
@@ -25,6 +26,7 @@ const BookmarkInteractiveFileFormatString = `# Bookmark
 `
 
 const (
+	BookmarkInteractiveFileSectionHeader     = "# Bookmark"
 	BookmarkInteractiveFileNameHeader        = "## Title"
 	BookmarkInteractiveFileUrlHeader         = "## URL"
 	BookmarkInteractiveFileDescriptionHeader = "## Description"
@@ -38,13 +40,20 @@ func subStringMatches(query, source string) bool {
 }
 
 type Bookmark struct {
-	Number      int       `json:"index"`
-	Url         Url       `json:"uri"`
-	Name        string    `json:"title"`
-	Description string    `json:"description"`
-	Tags        TagList   `json:"tags"`
-	WhenAdded   time.Time `json:"whenAdded"`
-	LastUpdated time.Time `json:"lastUpdated"`
+	Number      int        `json:"index"`
+	Url         Url        `json:"uri"`
+	Name        string     `json:"title"`
+	Description string     `json:"description"`
+	Tags        TagList    `json:"tags"`
+	WhenAdded   time.Time  `json:"whenAdded"`
+	LastUpdated time.Time  `json:"lastUpdated"`
+	LinkCheck   *LinkCheck `json:"linkCheck,omitempty"`
+
+	// Previous URLs of a bookmark that has moved
+	Aliases []string `json:"aliases,omitempty"`
+
+	Snapshots []Snapshot `json:"snapshots,omitempty"`
+	Metadata  *Metadata  `json:"metadata,omitempty"`
 }
 
 func (bm Bookmark) HasName() bool {
@@ -63,6 +72,39 @@ func (bm *Bookmark) DescriptionMatches(query string) bool {
 	return subStringMatches(query, bm.Description)
 }
 
+// Checks the URL and any previous URLs of the bookmark
+func (bm *Bookmark) HasUrl(url string) bool {
+	if bm.Url.String() == url {
+		return true
+	}
+
+	for _, a := range bm.Aliases {
+		if a == url {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (bm *Bookmark) AddSnapshot(s Snapshot) {
+	// Unchanged page only updates the time of the latest snapshot
+	if latest := bm.LatestSnapshot(); latest != nil && latest.File == s.File {
+		latest.Taken = s.Taken
+		return
+	}
+
+	bm.Snapshots = append(bm.Snapshots, s)
+}
+
+func (bm *Bookmark) LatestSnapshot() *Snapshot {
+	if len(bm.Snapshots) == 0 {
+		return nil
+	}
+
+	return &(bm.Snapshots[len(bm.Snapshots)-1])
+}
+
 func (bm *Bookmark) FormatAsInteractiveFileString() string {
 	return fmt.Sprintf(
 		BookmarkInteractiveFileFormatString,
@@ -181,6 +223,62 @@ func (bmks *BookmarkLibrary) GetByNumber(number int) (*Bookmark, error) {
 	}
 }
 
+// Finds a bookmark by its current URL, or failing that by a previous URL
+func (bmks *BookmarkLibrary) GetByUrl(url string) (*Bookmark, error) {
+	for idx, bm := range bmks.Bookmarks {
+		if bm.Url.String() == url {
+			return &(bmks.Bookmarks[idx]), nil
+		}
+	}
+
+	for idx, bm := range bmks.Bookmarks {
+		if bm.HasUrl(url) {
+			return &(bmks.Bookmarks[idx]), nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("No bookmark with URL %s found", url))
+}
+
+// Changes the URL of a bookmark, keeping the old URL as an alias
+func (bmks *BookmarkLibrary) MoveUrl(number int, url string) error {
+	bm, err := bmks.GetByNumber(number)
+	if err != nil {
+		return err
+	}
+
+	var newUrl Url
+	if err := newUrl.Parse(url); err != nil {
+		return err
+	}
+
+	oldUrl := bm.Url.String()
+	if newUrl.String() == oldUrl {
+		return nil
+	}
+
+	// URLs must stay unique
+	for _, other := range bmks.Bookmarks {
+		if other.Number != number && other.Url.String() == newUrl.String() {
+			return errors.New(fmt.Sprintf("Bookmark URL %s already used by bookmark %d", newUrl.String(), other.Number))
+		}
+	}
+
+	// Moving back to a previous URL removes it from the aliases
+	var aliases []string
+	for _, a := range bm.Aliases {
+		if a != newUrl.String() && a != oldUrl {
+			aliases = append(aliases, a)
+		}
+	}
+	bm.Aliases = append(aliases, oldUrl)
+
+	bm.Url = newUrl
+	bm.MarkUpdated()
+
+	return nil
+}
+
 func (bmks *BookmarkLibrary) DeleteByNumber(number int) error {
 	i, err := bmks.searchByNumber(number)
 	if err != nil {
@@ -210,14 +308,85 @@ func (bmks *BookmarkLibrary) NewEntry() *Bookmark {
 	return &(bmks.Bookmarks[bmks.Len()-1])
 }
 
+type MergeResult int
+
+const (
+	MergeAdded MergeResult = iota
+	MergeMerged
+	MergeSkipped
+)
+
+// Adds a bookmark from an external source, merging it into any existing
+// bookmark with the same URL
+func (bmks *BookmarkLibrary) Merge(bm Bookmark) (*Bookmark, MergeResult) {
+	existing, err := bmks.GetByUrl(bm.Url.String())
+	if err != nil {
+		newBm := bmks.NewEntry()
+		newBm.Url = bm.Url
+		newBm.Name = bm.Name
+		newBm.Description = bm.Description
+		newBm.Tags.Clear()
+		for _, t := range bm.Tags.Tags {
+			newBm.Tags.Append(t)
+		}
+
+		// Keep timestamps from the source where known
+		if !bm.WhenAdded.IsZero() {
+			newBm.WhenAdded = bm.WhenAdded
+		}
+		if !bm.LastUpdated.IsZero() {
+			newBm.LastUpdated = bm.LastUpdated
+		}
+
+		return newBm, MergeAdded
+	}
+
+	changed := false
+
+	for _, t := range bm.Tags.Tags {
+		if !existing.Tags.Contains(t) {
+			existing.Tags.Append(t)
+			changed = true
+		}
+	}
+
+	if !existing.HasName() && bm.HasName() {
+		existing.Name = bm.Name
+		changed = true
+	}
+
+	if len(existing.Description) == 0 && len(bm.Description) > 0 {
+		existing.Description = bm.Description
+		changed = true
+	}
+
+	if !changed {
+		return existing, MergeSkipped
+	}
+
+	existing.MarkUpdated()
+	return existing, MergeMerged
+}
+
 func (bmks *BookmarkLibrary) GetAllTags() AllTags {
 	var tags AllTags
 	tags.Count = make(TagCount)
+	tags.Total = make(TagCount)
 
 	for _, bm := range bmks.Bookmarks {
+		// Each bookmark counts once towards every level above its tags
+		var levels TagList
 		for _, t := range bm.Tags.Tags {
 			tags.Tags.Append(t)
 			tags.Count[t]++
+
+			for _, a := range TagAndAncestors(t) {
+				levels.Append(a)
+			}
+		}
+
+		for _, t := range levels.Tags {
+			tags.Total[t]++
 		}
 	}
 
@@ -226,6 +395,62 @@ func (bmks *BookmarkLibrary) GetAllTags() AllTags {
 	return tags
 }
 
+// Renames a tag on every bookmark, moving any tags below it in the hierarchy
+// with it, and returns the number of bookmarks changed
+func (bmks *BookmarkLibrary) RenameTag(from, to string) int {
+	changed := 0
+
+	for i := range bmks.Bookmarks {
+		bm := &(bmks.Bookmarks[i])
+
+		// Removing tags changes the list, so work on a copy
+		var renamed []string
+		for _, t := range append([]string{}, bm.Tags.Tags...) {
+			if newTag, ok := RenameTag(t, from, to); ok {
+				bm.Tags.Remove(t)
+				renamed = append(renamed, newTag)
+			}
+		}
+
+		if len(renamed) == 0 {
+			continue
+		}
+
+		for _, t := range renamed {
+			bm.Tags.Append(t)
+		}
+		bm.MarkUpdated()
+		changed++
+	}
+
+	return changed
+}
+
+// Removes a tag, and any tags below it in the hierarchy, from every bookmark
+// and returns the number of bookmarks changed
+func (bmks *BookmarkLibrary) DeleteTag(tag string) int {
+	changed := 0
+
+	for i := range bmks.Bookmarks {
+		bm := &(bmks.Bookmarks[i])
+
+		removed := false
+		for _, t := range append([]string{}, bm.Tags.Tags...) {
+			if IsTagOrDescendant(t, tag) {
+				bm.Tags.Remove(t)
+				removed = true
+			}
+		}
+
+		if removed {
+			bm.MarkUpdated()
+			changed++
+		}
+	}
+
+	return changed
+}
+
 func (bmks *BookmarkLibrary) searchByNumber(number int) (int, error) {
 	for idx, bm := range bmks.Bookmarks {
 		if bm.Number == number {
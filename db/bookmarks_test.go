@@ -309,3 +309,160 @@ func TestBookmarkLibraryGetAllTags(t *testing.T) {
 	assert.Equal(t, 1, tags.Count["weather"])
 	assert.Equal(t, 2, tags.Count["software"])
 }
+
+func TestBookmarkLibraryGetAllTagsHierarchy(t *testing.T) {
+	bmks := createTestLibrary()
+	bmks.Bookmarks[0].Tags = db.TagList{Tags: []string{"lang", "lang/go"}}
+	bmks.Bookmarks[1].Tags = db.TagList{Tags: []string{"lang/rust"}}
+
+	tags := bmks.GetAllTags()
+
+	assert.Equal(t, 1, tags.Count["lang"])
+	assert.Equal(t, 2, tags.Total["lang"])
+	assert.Equal(t, 1, tags.Total["lang/go"])
+	assert.Equal(t, 0, tags.Count["lang/software"])
+}
+
+func TestBookmarkLibraryRenameTag(t *testing.T) {
+	bmks := createTestLibrary()
+	bmks.Bookmarks[0].Tags = db.TagList{Tags: []string{"lang", "lang/go", "language"}}
+	bmks.Bookmarks[1].Tags = db.TagList{Tags: []string{"code/go", "lang/go"}}
+
+	assert.Equal(t, 2, bmks.RenameTag("lang", "code"))
+
+	assert.Equal(t, []string{"code", "code/go", "language"}, bmks.Bookmarks[0].Tags.Tags)
+	assert.Equal(t, []string{"code/go"}, bmks.Bookmarks[1].Tags.Tags)
+	assert.Equal(t, []string{"news", "software"}, bmks.Bookmarks[2].Tags.Tags)
+}
+
+func TestBookmarkLibraryDeleteTag(t *testing.T) {
+	bmks := createTestLibrary()
+	bmks.Bookmarks[0].Tags = db.TagList{Tags: []string{"lang", "lang/go", "language"}}
+
+	assert.Equal(t, 1, bmks.DeleteTag("lang"))
+	assert.Equal(t, []string{"language"}, bmks.Bookmarks[0].Tags.Tags)
+
+	assert.Equal(t, 2, bmks.DeleteTag("software"))
+	assert.Equal(t, []string{}, bmks.Bookmarks[1].Tags.Tags)
+	assert.Equal(t, []string{"news"}, bmks.Bookmarks[2].Tags.Tags)
+}
+
+func TestBookmarkLibraryGetByUrl(t *testing.T) {
+	bmks := createTestLibrary()
+
+	bm, err := bmks.GetByUrl("https://facebook.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, bm.Number)
+
+	bm, err = bmks.GetByUrl("https://example.com")
+	assert.NotNil(t, err)
+	assert.Nil(t, bm)
+}
+
+func TestBookmarkLibraryMoveUrl(t *testing.T) {
+	bmks := createTestLibrary()
+
+	assert.Nil(t, bmks.MoveUrl(2, "https://www.facebook.com/"))
+
+	bm, err := bmks.GetByNumber(2)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://www.facebook.com/", bm.Url.String())
+	assert.Equal(t, []string{"https://facebook.com"}, bm.Aliases)
+	assert.Nil(t, bmks.Verify())
+
+	// Old URL still finds the bookmark
+	bm, err = bmks.GetByUrl("https://facebook.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, bm.Number)
+}
+
+func TestBookmarkLibraryMoveUrlBack(t *testing.T) {
+	bmks := createTestLibrary()
+
+	assert.Nil(t, bmks.MoveUrl(2, "https://www.facebook.com/"))
+	assert.Nil(t, bmks.MoveUrl(2, "https://facebook.com"))
+
+	bm, _ := bmks.GetByNumber(2)
+	assert.Equal(t, "https://facebook.com", bm.Url.String())
+	assert.Equal(t, []string{"https://www.facebook.com/"}, bm.Aliases)
+}
+
+func TestBookmarkLibraryMoveUrlExisting(t *testing.T) {
+	bmks := createTestLibrary()
+
+	assert.NotNil(t, bmks.MoveUrl(2, "https://github.com"))
+
+	bm, _ := bmks.GetByNumber(2)
+	assert.Equal(t, "https://facebook.com", bm.Url.String())
+	assert.Nil(t, bm.Aliases)
+	assert.Nil(t, bmks.Verify())
+}
+
+func TestBookmarkLibraryMergeAdded(t *testing.T) {
+	bmks := createTestLibrary()
+
+	when := time.Date(2018, time.November, 2, 10, 0, 0, 0, time.UTC)
+	bm := db.Bookmark{
+		Name:      "Example",
+		Tags:      db.TagList{Tags: []string{"test"}},
+		WhenAdded: when,
+	}
+	bm.Url.Parse("https://example.com")
+
+	added, result := bmks.Merge(bm)
+	assert.Equal(t, db.MergeAdded, result)
+	assert.Equal(t, 4, added.Number)
+	assert.Equal(t, "Example", added.Name)
+	assert.Equal(t, []string{"test"}, added.Tags.Tags)
+	assert.Equal(t, when, added.WhenAdded)
+	assert.Equal(t, 4, bmks.Len())
+	assert.Nil(t, bmks.Verify())
+}
+
+func TestBookmarkLibraryMergeMerged(t *testing.T) {
+	bmks := createTestLibrary()
+
+	bm := db.Bookmark{
+		Name:        "Other name",
+		Description: "Social network",
+		Tags:        db.TagList{Tags: []string{"social", "software"}},
+	}
+	bm.Url.Parse("https://facebook.com")
+
+	merged, result := bmks.Merge(bm)
+	assert.Equal(t, db.MergeMerged, result)
+	assert.Equal(t, 2, merged.Number)
+	assert.Equal(t, "two", merged.Name)
+	assert.Equal(t, "Social network", merged.Description)
+	assert.Equal(t, []string{"social", "software"}, merged.Tags.Tags)
+	assert.Equal(t, 3, bmks.Len())
+}
+
+func TestBookmarkLibraryMergeSkipped(t *testing.T) {
+	bmks := createTestLibrary()
+
+	bm := db.Bookmark{
+		Name: "two",
+		Tags: db.TagList{Tags: []string{"software"}},
+	}
+	bm.Url.Parse("https://facebook.com")
+
+	_, result := bmks.Merge(bm)
+	assert.Equal(t, db.MergeSkipped, result)
+	assert.Equal(t, 3, bmks.Len())
+}
+
+func TestBookmarkLibraryMergeAlias(t *testing.T) {
+	bmks := createTestLibrary()
+	assert.Nil(t, bmks.MoveUrl(2, "https://www.facebook.com/"))
+
+	bm := db.Bookmark{
+		Tags: db.TagList{Tags: []string{"social"}},
+	}
+	bm.Url.Parse("https://facebook.com")
+
+	merged, result := bmks.Merge(bm)
+	assert.Equal(t, db.MergeMerged, result)
+	assert.Equal(t, 2, merged.Number)
+	assert.Equal(t, 3, bmks.Len())
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mark the matching terms in snippets returned by ContentIndex.Search
+const (
+	ContentSnippetMatchStart = "\x02"
+	ContentSnippetMatchEnd   = "\x03"
+)
+
+// Words either side of a match included in a snippet
+const contentSnippetLength = 16
+
+const contentIndexSchema = `CREATE VIRTUAL TABLE IF NOT EXISTS pages USING fts5(
+	uri UNINDEXED,
+	indexed UNINDEXED,
+	content,
+	tokenize = 'porter unicode61'
+)`
+
+type ContentMatch struct {
+	Url     string
+	Snippet string
+}
+
+// Full text index of the content of bookmarked pages, kept separately from
+// the library as it can be rebuilt at any time
+type ContentIndex struct {
+	Filename string
+
+	db *sql.DB
+}
+
+// Content index kept next to a library file
+func ContentIndexForFile(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + ".content.db"
+}
+
+func OpenContentIndex(filename string) (*ContentIndex, error) {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(contentIndexSchema)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &ContentIndex{
+		Filename: filename,
+		db:       db,
+	}, nil
+}
+
+func (ci *ContentIndex) Close() error {
+	return ci.db.Close()
+}
+
+// Replaces the indexed content of a page
+func (ci *ContentIndex) Put(url, content string) error {
+	tx, err := ci.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM pages WHERE uri = ?", url)
+	if err == nil {
+		_, err = tx.Exec("INSERT INTO pages (uri, indexed, content) VALUES (?, ?, ?)",
+			url, time.Now().Format(time.RFC3339Nano), content)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (ci *ContentIndex) Delete(url string) error {
+	_, err := ci.db.Exec("DELETE FROM pages WHERE uri = ?", url)
+	return err
+}
+
+// Returns when each indexed page was last indexed
+func (ci *ContentIndex) Indexed() (map[string]time.Time, error) {
+	rows, err := ci.db.Query("SELECT uri, indexed FROM pages")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexed := make(map[string]time.Time)
+	for rows.Next() {
+		var url, when string
+		if err = rows.Scan(&url, &when); err != nil {
+			return nil, err
+		}
+
+		indexed[url], err = time.Parse(time.RFC3339Nano, when)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return indexed, rows.Err()
+}
+
+// Finds pages containing all of the words in the query, best match first
+func (ci *ContentIndex) Search(query string) ([]ContentMatch, error) {
+	var terms []string
+	for _, t := range strings.Fields(query) {
+		terms = append(terms, quoteFTSString(t))
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	rows, err := ci.db.Query(
+		`SELECT uri, snippet(pages, 2, ?, ?, '…', ?) FROM pages WHERE pages MATCH ? ORDER BY rank`,
+		ContentSnippetMatchStart, ContentSnippetMatchEnd, contentSnippetLength, strings.Join(terms, " "))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []ContentMatch
+	for rows.Next() {
+		var m ContentMatch
+		if err = rows.Scan(&m.Url, &m.Snippet); err != nil {
+			return nil, err
+		}
+
+		// Snippets span lines of the page
+		m.Snippet = strings.Join(strings.Fields(m.Snippet), " ")
+
+		matches = append(matches, m)
+	}
+
+	return matches, rows.Err()
+}
@@ -0,0 +1,85 @@
+package db_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func createTestContentIndex(t *testing.T) (*db.ContentIndex, func()) {
+	dir, _ := ioutil.TempDir("", "voile")
+
+	ci, err := db.OpenContentIndex(filepath.Join(dir, "bookmarks.content.db"))
+	require.NoError(t, err)
+
+	return ci, func() {
+		ci.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestContentIndexForFile(t *testing.T) {
+	assert.Equal(t, filepath.Join("library", "bookmarks.content.db"),
+		db.ContentIndexForFile(filepath.Join("library", "bookmarks.json")))
+}
+
+func TestContentIndexSearch(t *testing.T) {
+	ci, cleanup := createTestContentIndex(t)
+	defer cleanup()
+
+	assert.Nil(t, ci.Put("https://one.example.com", "Baking bread at home\nA guide to sourdough starters and ovens"))
+	assert.Nil(t, ci.Put("https://two.example.com", "Repairing bicycles\nHow to fix a puncture"))
+
+	matches, err := ci.Search("sourdough oven")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matches))
+	assert.Equal(t, "https://one.example.com", matches[0].Url)
+	assert.Contains(t, matches[0].Snippet, db.ContentSnippetMatchStart+"sourdough"+db.ContentSnippetMatchEnd)
+
+	matches, err = ci.Search("puncture")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(matches))
+	assert.Equal(t, "https://two.example.com", matches[0].Url)
+
+	matches, err = ci.Search("\"quoted")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(matches))
+}
+
+func TestContentIndexPutReplaces(t *testing.T) {
+	ci, cleanup := createTestContentIndex(t)
+	defer cleanup()
+
+	assert.Nil(t, ci.Put("https://one.example.com", "Old content"))
+	assert.Nil(t, ci.Put("https://one.example.com", "New content"))
+
+	matches, _ := ci.Search("old")
+	assert.Equal(t, 0, len(matches))
+
+	matches, _ = ci.Search("new")
+	assert.Equal(t, 1, len(matches))
+
+	indexed, err := ci.Indexed()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(indexed))
+}
+
+func TestContentIndexDelete(t *testing.T) {
+	ci, cleanup := createTestContentIndex(t)
+	defer cleanup()
+
+	assert.Nil(t, ci.Put("https://one.example.com", "Some content"))
+	assert.Nil(t, ci.Delete("https://one.example.com"))
+
+	matches, _ := ci.Search("content")
+	assert.Equal(t, 0, len(matches))
+
+	indexed, _ := ci.Indexed()
+	assert.Equal(t, 0, len(indexed))
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Groups bookmarks whose URLs (or previous URLs) normalise to the same
+// address, each group ordered by bookmark number
+func (bmks *BookmarkLibrary) FindDuplicates() [][]*Bookmark {
+	// Union find over bookmark indices
+	parent := make([]int, bmks.Len())
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	firstWithUrl := make(map[string]int)
+	for i := range bmks.Bookmarks {
+		bm := &(bmks.Bookmarks[i])
+
+		urls := []Url{bm.Url}
+		for _, a := range bm.Aliases {
+			var alias Url
+			if alias.Parse(a) == nil {
+				urls = append(urls, alias)
+			}
+		}
+
+		for _, u := range urls {
+			key := u.Normalised()
+			if j, ok := firstWithUrl[key]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				firstWithUrl[key] = i
+			}
+		}
+	}
+
+	groups := make(map[int][]*Bookmark)
+	for i := range bmks.Bookmarks {
+		root := find(i)
+		groups[root] = append(groups[root], &(bmks.Bookmarks[i]))
+	}
+
+	var duplicates [][]*Bookmark
+	for _, g := range groups {
+		if len(g) < 2 {
+			continue
+		}
+
+		sort.Slice(g, func(i, j int) bool { return g[i].Number < g[j].Number })
+		duplicates = append(duplicates, g)
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i][0].Number < duplicates[j][0].Number
+	})
+
+	return duplicates
+}
+
+// Merges other bookmarks into the one numbered keep, which keeps its URL and
+// gains the others' URLs as aliases, then removes the others
+func (bmks *BookmarkLibrary) MergeDuplicates(keep int, others []int) error {
+	for _, number := range others {
+		if number == keep {
+			return errors.New(fmt.Sprintf("Cannot merge bookmark %d into itself", keep))
+		}
+		if _, err := bmks.GetByNumber(number); err != nil {
+			return err
+		}
+	}
+
+	bm, err := bmks.GetByNumber(keep)
+	if err != nil {
+		return err
+	}
+
+	descriptions := []string{}
+	if len(bm.Description) > 0 {
+		descriptions = append(descriptions, bm.Description)
+	}
+
+	for _, number := range others {
+		other, _ := bmks.GetByNumber(number)
+
+		for _, t := range other.Tags.Tags {
+			if !bm.Tags.Contains(t) {
+				bm.Tags.Append(t)
+			}
+		}
+
+		if !bm.HasName() && other.HasName() {
+			bm.Name = other.Name
+		}
+
+		if len(other.Description) > 0 && !containsString(descriptions, other.Description) {
+			descriptions = append(descriptions, other.Description)
+		}
+
+		for _, u := range append([]string{other.Url.String()}, other.Aliases...) {
+			if u != bm.Url.String() && !containsString(bm.Aliases, u) {
+				bm.Aliases = append(bm.Aliases, u)
+			}
+		}
+
+		if other.WhenAdded.Before(bm.WhenAdded) {
+			bm.WhenAdded = other.WhenAdded
+		}
+
+		bm.Snapshots = append(bm.Snapshots, other.Snapshots...)
+		if bm.Metadata == nil {
+			bm.Metadata = other.Metadata
+		}
+	}
+
+	bm.Description = strings.Join(descriptions, "\n\n")
+	bm.MarkUpdated()
+
+	// Latest snapshot is last
+	sort.SliceStable(bm.Snapshots, func(i, j int) bool {
+		return bm.Snapshots[i].Taken.Before(bm.Snapshots[j].Taken)
+	})
+
+	for _, number := range others {
+		if err := bmks.DeleteByNumber(number); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func createTestDuplicateLibrary() db.BookmarkLibrary {
+	var bmks db.BookmarkLibrary
+
+	for _, u := range []string{
+		"https://x.com/a",
+		"https://example.com",
+		"http://www.x.com/a/",
+		"https://x.com/a?utm_source=feed",
+		"https://example.com/other",
+	} {
+		bm := bmks.NewEntry()
+		bm.Url.Parse(u)
+	}
+
+	return bmks
+}
+
+func TestBookmarkLibraryFindDuplicates(t *testing.T) {
+	bmks := createTestDuplicateLibrary()
+
+	duplicates := bmks.FindDuplicates()
+	assert.Equal(t, 1, len(duplicates))
+	assert.Equal(t, 3, len(duplicates[0]))
+	assert.Equal(t, 1, duplicates[0][0].Number)
+	assert.Equal(t, 3, duplicates[0][1].Number)
+	assert.Equal(t, 4, duplicates[0][2].Number)
+}
+
+func TestBookmarkLibraryFindDuplicatesAlias(t *testing.T) {
+	bmks := createTestDuplicateLibrary()
+	assert.Nil(t, bmks.MoveUrl(2, "https://example.com/moved"))
+	assert.Nil(t, bmks.DeleteByNumber(3))
+	assert.Nil(t, bmks.DeleteByNumber(4))
+
+	bm := bmks.NewEntry()
+	bm.Url.Parse("https://www.example.com/")
+
+	duplicates := bmks.FindDuplicates()
+	assert.Equal(t, 1, len(duplicates))
+	assert.Equal(t, 2, len(duplicates[0]))
+	assert.Equal(t, 2, duplicates[0][0].Number)
+	assert.Equal(t, bm.Number, duplicates[0][1].Number)
+}
+
+func TestBookmarkLibraryMergeDuplicates(t *testing.T) {
+	bmks := createTestDuplicateLibrary()
+
+	one, _ := bmks.GetByNumber(1)
+	one.Name = ""
+	one.Description = "First"
+	one.Tags.Append("a")
+
+	three, _ := bmks.GetByNumber(3)
+	three.Name = "Three"
+	three.Description = "Third"
+	three.Tags.Append("b")
+	three.WhenAdded = time.Date(2018, time.November, 2, 10, 0, 0, 0, time.UTC)
+
+	four, _ := bmks.GetByNumber(4)
+	four.Description = "First"
+	four.Tags.Append("a")
+	four.Tags.Append("c")
+
+	assert.Nil(t, bmks.MergeDuplicates(1, []int{3, 4}))
+	assert.Equal(t, 3, bmks.Len())
+	assert.Nil(t, bmks.Verify())
+
+	bm, err := bmks.GetByNumber(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://x.com/a", bm.Url.String())
+	assert.Equal(t, "Three", bm.Name)
+	assert.Equal(t, "First\n\nThird", bm.Description)
+	assert.Equal(t, []string{"a", "b", "c"}, bm.Tags.Tags)
+	assert.Equal(t, []string{"http://www.x.com/a/", "https://x.com/a?utm_source=feed"}, bm.Aliases)
+	assert.Equal(t, time.Date(2018, time.November, 2, 10, 0, 0, 0, time.UTC), bm.WhenAdded)
+
+	assert.Equal(t, 0, len(bmks.FindDuplicates()))
+}
+
+func TestBookmarkLibraryMergeDuplicatesInvalid(t *testing.T) {
+	bmks := createTestDuplicateLibrary()
+
+	assert.NotNil(t, bmks.MergeDuplicates(1, []int{1}))
+	assert.NotNil(t, bmks.MergeDuplicates(1, []int{10}))
+	assert.NotNil(t, bmks.MergeDuplicates(10, []int{1}))
+	assert.Equal(t, 5, bmks.Len())
+}
+
+func TestBookmarkLibraryFindDuplicatesOpaque(t *testing.T) {
+	var bmks db.BookmarkLibrary
+
+	for _, u := range []string{
+		"mailto:a@example.com",
+		"mailto:b@example.com",
+		"javascript:void(0)",
+		"urn:isbn:0451450523",
+	} {
+		bm := bmks.NewEntry()
+		bm.Url.Parse(u)
+	}
+
+	assert.Equal(t, 0, len(bmks.FindDuplicates()))
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A section of a file editing several bookmarks, Number is zero for sections
+// added in the editor
+type InteractiveFileSection struct {
+	Number int
+	Data   string
+}
+
+// Result of applying an edited file of several bookmarks
+type InteractiveFileChanges struct {
+	// Bookmarks that were changed
+	Updated []int
+	// Bookmarks created from sections added in the editor
+	Added []int
+	// Bookmarks whose section was removed, these are not deleted
+	Removed []int
+}
+
+// Formats a bookmark as a section of a file editing several bookmarks, headed
+// with the bookmark number
+func (bm *Bookmark) FormatAsInteractiveFileSection() string {
+	return fmt.Sprintf("%s %d\n", BookmarkInteractiveFileSectionHeader, bm.Number) +
+		fmt.Sprintf(bookmarkInteractiveFileFieldsFormatString,
+			bm.Name, bm.Url.String(), bm.Description, bm.Tags.MultilineString())
+}
+
+// Formats several bookmarks for editing at once
+func FormatAsInteractiveFile(bms []*Bookmark) string {
+	retVal := "# Sections are matched to bookmarks by number\n"
+	retVal += fmt.Sprintf("# Add a bookmark with a section headed \"%s\" and no number\n", BookmarkInteractiveFileSectionHeader)
+	retVal += "# Removing a section offers to delete the bookmark\n\n"
+
+	for _, bm := range bms {
+		retVal += bm.FormatAsInteractiveFileSection() + "\n"
+	}
+
+	return retVal
+}
+
+// Splits a file holding several bookmarks into one section per bookmark
+func ParseInteractiveFile(data string) ([]InteractiveFileSection, error) {
+	var sections []InteractiveFileSection
+	var current *InteractiveFileSection
+	seen := make(map[int]bool)
+
+	for _, l := range strings.SplitAfter(data, "\n") {
+		trimmed := strings.TrimSpace(l)
+
+		if trimmed == BookmarkInteractiveFileSectionHeader || strings.HasPrefix(trimmed, BookmarkInteractiveFileSectionHeader+" ") {
+			number := 0
+
+			if n := strings.TrimSpace(strings.TrimPrefix(trimmed, BookmarkInteractiveFileSectionHeader)); len(n) > 0 {
+				var err error
+				number, err = strconv.Atoi(n)
+				if err != nil || number <= 0 {
+					return nil, errors.New(fmt.Sprintf("Invalid bookmark section header \"%s\"", trimmed))
+				}
+
+				if seen[number] {
+					return nil, errors.New(fmt.Sprintf("Bookmark %d appears more than once", number))
+				}
+				seen[number] = true
+			}
+
+			sections = append(sections, InteractiveFileSection{Number: number})
+			current = &(sections[len(sections)-1])
+			continue
+		}
+
+		if current != nil {
+			current.Data += l
+		}
+	}
+
+	return sections, nil
+}
+
+// Applies a file produced by FormatAsInteractiveFile for the bookmarks numbered
+// edited. Nothing is changed if any section is invalid.
+func (bmks *BookmarkLibrary) UpdateFromInteractiveFile(data string, edited []int) (InteractiveFileChanges, error) {
+	var changes InteractiveFileChanges
+
+	sections, err := ParseInteractiveFile(data)
+	if err != nil {
+		return changes, err
+	}
+
+	isEdited := make(map[int]bool)
+	for _, n := range edited {
+		isEdited[n] = true
+	}
+
+	// Parse every section before changing anything
+	updated := make(map[int]Bookmark)
+	var added []Bookmark
+	present := make(map[int]bool)
+
+	for _, s := range sections {
+		if s.Number == 0 {
+			var bm Bookmark
+			if err = bm.UpdateFromInteractiveFileString(s.Data); err != nil {
+				return changes, err
+			}
+
+			if len(bm.Url.String()) == 0 {
+				return changes, errors.New("Added bookmarks must have a URL")
+			}
+			if existing, err := bmks.GetByUrl(bm.Url.String()); err == nil {
+				return changes, errors.New(fmt.Sprintf("%s is already bookmarked as %d", bm.Url.String(), existing.Number))
+			}
+
+			added = append(added, bm)
+			continue
+		}
+
+		if !isEdited[s.Number] {
+			return changes, errors.New(fmt.Sprintf("Bookmark %d was not being edited", s.Number))
+		}
+		present[s.Number] = true
+
+		original, err := bmks.GetByNumber(s.Number)
+		if err != nil {
+			return changes, err
+		}
+
+		bm := *original
+		if err = bm.UpdateFromInteractiveFileString(s.Data); err != nil {
+			return changes, err
+		}
+
+		// Leave untouched bookmarks alone so their last updated time stays
+		if bm.Name != original.Name || bm.Url.String() != original.Url.String() ||
+			bm.Description != original.Description || bm.Tags.String() != original.Tags.String() {
+			updated[s.Number] = bm
+		}
+	}
+
+	// Apply the changes to a copy, as sections can also conflict with each
+	// other (e.g. two added with the same URL)
+	result := BookmarkLibrary{
+		Bookmarks: append([]Bookmark{}, bmks.Bookmarks...),
+	}
+
+	for _, n := range edited {
+		if bm, ok := updated[n]; ok {
+			original, _ := result.GetByNumber(n)
+			*original = bm
+			changes.Updated = append(changes.Updated, n)
+		} else if !present[n] {
+			changes.Removed = append(changes.Removed, n)
+		}
+	}
+
+	for _, bm := range added {
+		entry := result.NewEntry()
+		entry.Name = bm.Name
+		entry.Url = bm.Url
+		entry.Description = bm.Description
+		entry.Tags = bm.Tags
+		changes.Added = append(changes.Added, entry.Number)
+	}
+
+	if err = result.Verify(); err != nil {
+		return InteractiveFileChanges{}, err
+	}
+
+	bmks.Bookmarks = result.Bookmarks
+
+	return changes, nil
+}
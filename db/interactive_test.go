@@ -0,0 +1,145 @@
+package db_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func editedFile(bmks *db.BookmarkLibrary) string {
+	var bms []*db.Bookmark
+	for i := range bmks.Bookmarks {
+		bms = append(bms, &(bmks.Bookmarks[i]))
+	}
+	return db.FormatAsInteractiveFile(bms) + "# Existing tags:\n# news\n"
+}
+
+func TestParseInteractiveFile(t *testing.T) {
+	bmks := createTestLibrary()
+
+	sections, err := db.ParseInteractiveFile(editedFile(&bmks))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(sections))
+
+	for i, section := range sections {
+		assert.Equal(t, bmks.Bookmarks[i].Number, section.Number)
+
+		var bm db.Bookmark
+		assert.Nil(t, bm.UpdateFromInteractiveFileString(section.Data))
+		assert.Equal(t, bmks.Bookmarks[i].Name, bm.Name)
+		assert.Equal(t, bmks.Bookmarks[i].Url.String(), bm.Url.String())
+		assert.Equal(t, bmks.Bookmarks[i].Tags.Tags, bm.Tags.Tags)
+	}
+}
+
+func TestParseInteractiveFileEmpty(t *testing.T) {
+	sections, err := db.ParseInteractiveFile("# Just a comment\n")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(sections))
+}
+
+func TestParseInteractiveFileNewSection(t *testing.T) {
+	sections, err := db.ParseInteractiveFile("# Bookmark 4\n## Title\nfour\n# Bookmark\n## Title\nnew\n")
+	assert.Nil(t, err)
+	assert.Equal(t, []db.InteractiveFileSection{
+		{Number: 4, Data: "## Title\nfour\n"},
+		{Number: 0, Data: "## Title\nnew\n"},
+	}, sections)
+}
+
+func TestParseInteractiveFileInvalidHeader(t *testing.T) {
+	_, err := db.ParseInteractiveFile("# Bookmark four\n")
+	assert.NotNil(t, err)
+
+	_, err = db.ParseInteractiveFile("# Bookmark 2\n# Bookmark 2\n")
+	assert.NotNil(t, err)
+
+	// Numbers start from 1
+	_, err = db.ParseInteractiveFile("# Bookmark 0\n")
+	assert.NotNil(t, err)
+}
+
+func TestUpdateFromInteractiveFileUnchanged(t *testing.T) {
+	bmks := createTestLibrary()
+	original := createTestLibrary()
+
+	changes, err := bmks.UpdateFromInteractiveFile(editedFile(&bmks), []int{1, 2, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, db.InteractiveFileChanges{}, changes)
+	assert.Equal(t, original, bmks)
+}
+
+func TestUpdateFromInteractiveFileEditAddRemove(t *testing.T) {
+	bmks := createTestLibrary()
+
+	data := editedFile(&bmks)
+	data = strings.Replace(data, "two\n", "second\n", 1)
+	data = strings.Replace(data, "# Bookmark 3\n", "# Bookmark\n", 1)
+	data = strings.Replace(data, "https://bbc.co.uk", "https://example.com", 1)
+	data = data[:strings.Index(data, "# Bookmark 1\n")] + data[strings.Index(data, "# Bookmark 2\n"):]
+
+	changes, err := bmks.UpdateFromInteractiveFile(data, []int{1, 2, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, db.InteractiveFileChanges{
+		Updated: []int{2},
+		Added:   []int{4},
+		Removed: []int{1, 3},
+	}, changes)
+
+	// Removed bookmarks are only reported
+	assert.Equal(t, 4, bmks.Len())
+
+	bm, _ := bmks.GetByNumber(2)
+	assert.Equal(t, "second", bm.Name)
+
+	bm, _ = bmks.GetByNumber(4)
+	assert.Equal(t, "three", bm.Name)
+	assert.Equal(t, "https://example.com", bm.Url.String())
+	assert.Equal(t, []string{"news", "software"}, bm.Tags.Tags)
+}
+
+func TestUpdateFromInteractiveFileNotEdited(t *testing.T) {
+	bmks := createTestLibrary()
+	original := createTestLibrary()
+
+	data := strings.Replace(editedFile(&bmks), "two\n", "second\n", 1)
+
+	_, err := bmks.UpdateFromInteractiveFile(data, []int{1, 3})
+	assert.NotNil(t, err)
+	assert.Equal(t, original, bmks)
+}
+
+func TestUpdateFromInteractiveFileAddedExists(t *testing.T) {
+	bmks := createTestLibrary()
+	original := createTestLibrary()
+
+	data := strings.Replace(editedFile(&bmks), "# Bookmark 3\n", "# Bookmark\n", 1)
+	data = strings.Replace(data, "two\n", "second\n", 1)
+
+	_, err := bmks.UpdateFromInteractiveFile(data, []int{1, 2, 3})
+	assert.NotNil(t, err)
+	assert.Equal(t, original, bmks)
+}
+
+func TestUpdateFromInteractiveFileConflictingSections(t *testing.T) {
+	bmks := createTestLibrary()
+	original := createTestLibrary()
+
+	// Two added bookmarks with the same URL
+	added := "# Bookmark\n## Title\nnew\n## URL\nhttps://example.com\n"
+	data := editedFile(&bmks) + added + added
+
+	_, err := bmks.UpdateFromInteractiveFile(data, []int{1, 2, 3})
+	assert.NotNil(t, err)
+	assert.Equal(t, original, bmks)
+
+	// An added bookmark with the URL another was changed to
+	data = strings.Replace(editedFile(&bmks), "https://bbc.co.uk", "https://example.com", 1) + added
+
+	_, err = bmks.UpdateFromInteractiveFile(data, []int{1, 2, 3})
+	assert.NotNil(t, err)
+	assert.Equal(t, original, bmks)
+}
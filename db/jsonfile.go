@@ -0,0 +1,139 @@
+package db
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+type JSONFileStore struct {
+	Filename string
+	lock     fileLock
+
+	// Hash of the file contents when last loaded or saved, nil if the file
+	// did not exist
+	loaded     bool
+	loadedHash []byte
+}
+
+func NewJSONFileStore(filename string) *JSONFileStore {
+	return &JSONFileStore{
+		Filename: filename,
+		lock:     newFileLock(filename),
+	}
+}
+
+func (s *JSONFileStore) Path() string {
+	return s.Filename
+}
+
+func (s *JSONFileStore) Load() (BookmarkLibrary, error) {
+	var bmks BookmarkLibrary
+
+	// Read entire JSON file to string, a missing file is an empty library
+	raw, err := s.readFile()
+	if err != nil {
+		return bmks, err
+	}
+
+	s.loaded = true
+	s.loadedHash = hashContents(raw)
+
+	if raw == nil {
+		return bmks, nil
+	}
+
+	return ParseJSONLibrary(raw)
+}
+
+func (s *JSONFileStore) Save(bmks *BookmarkLibrary) error {
+	raw, err := FormatJSONLibrary(bmks)
+	if err != nil {
+		return err
+	}
+
+	// Make sure nothing else wrote to the file since it was loaded
+	if s.loaded {
+		current, err := s.readFile()
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(s.loadedHash, hashContents(current)) {
+			return ErrLibraryChanged
+		}
+	}
+
+	// Write JSON string to file
+	err = writeFileAtomic(s.Filename, raw, 0644)
+	if err != nil {
+		return err
+	}
+
+	s.loaded = true
+	s.loadedHash = hashContents(raw)
+
+	return nil
+}
+
+// Reads a library in the format of a JSON file store
+func ParseJSONLibrary(raw []byte) (BookmarkLibrary, error) {
+	var bmks BookmarkLibrary
+
+	// Load bookmarks from JSON
+	err := json.Unmarshal(raw, &(bmks.Bookmarks))
+	if err != nil {
+		return bmks, err
+	}
+
+	// Validate the loaded data
+	err = bmks.Verify()
+	if err != nil {
+		return bmks, err
+	}
+
+	sort.Sort(&bmks)
+
+	return bmks, nil
+}
+
+// Writes a library in the format of a JSON file store
+func FormatJSONLibrary(bmks *BookmarkLibrary) ([]byte, error) {
+	// Validate the bookmarks before saving
+	err := bmks.Verify()
+	if err != nil {
+		return nil, err
+	}
+
+	// Write bookmarks to indented JSON string
+	return json.MarshalIndent(bmks.Bookmarks, "", "  ")
+}
+
+func (s *JSONFileStore) Lock() error {
+	return s.lock.Lock()
+}
+
+func (s *JSONFileStore) Unlock() error {
+	return s.lock.Unlock()
+}
+
+// Returns nil contents if the file does not exist
+func (s *JSONFileStore) readFile() ([]byte, error) {
+	raw, err := ioutil.ReadFile(s.Filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return raw, err
+}
+
+func hashContents(raw []byte) []byte {
+	if raw == nil {
+		return nil
+	}
+
+	h := sha256.Sum256(raw)
+	return h[:]
+}
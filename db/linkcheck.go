@@ -0,0 +1,26 @@
+package db
+
+import (
+	"time"
+)
+
+// Result of the last request made to a bookmark's URL
+type LinkCheck struct {
+	Checked    time.Time `json:"checked"`
+	StatusCode int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+
+	// Final URL if the request was redirected
+	RedirectUrl string `json:"redirect,omitempty"`
+
+	// Set if every redirect followed was permanent
+	PermanentRedirect bool `json:"permanentRedirect,omitempty"`
+}
+
+func (lc *LinkCheck) IsDead() bool {
+	return len(lc.Error) > 0 || lc.StatusCode >= 400
+}
+
+func (lc *LinkCheck) IsRedirected() bool {
+	return len(lc.RedirectUrl) > 0
+}
@@ -0,0 +1,32 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func TestLinkCheckOk(t *testing.T) {
+	lc := db.LinkCheck{StatusCode: 200}
+	assert.False(t, lc.IsDead())
+	assert.False(t, lc.IsRedirected())
+}
+
+func TestLinkCheckNotFound(t *testing.T) {
+	lc := db.LinkCheck{StatusCode: 404}
+	assert.True(t, lc.IsDead())
+	assert.False(t, lc.IsRedirected())
+}
+
+func TestLinkCheckError(t *testing.T) {
+	lc := db.LinkCheck{Error: "no such host"}
+	assert.True(t, lc.IsDead())
+}
+
+func TestLinkCheckRedirected(t *testing.T) {
+	lc := db.LinkCheck{StatusCode: 200, RedirectUrl: "https://example.com"}
+	assert.False(t, lc.IsDead())
+	assert.True(t, lc.IsRedirected())
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// How long to wait for another process to release the library
+var LockTimeout = 10 * time.Second
+
+const lockRetryDelay = 100 * time.Millisecond
+
+var ErrLibraryChanged = errors.New("Bookmark library changed on disk since it was loaded, changes not saved")
+
+// Advisory lock on a file alongside the library, the library itself can not
+// be locked as it is replaced on every save
+type fileLock struct {
+	lock *flock.Flock
+}
+
+func newFileLock(filename string) fileLock {
+	return fileLock{
+		lock: flock.New(lockFilename(filename)),
+	}
+}
+
+// Lock file for a library, kept in the .git directory if the library is in a
+// Git repository so it never shows up as an untracked file
+func lockFilename(filename string) string {
+	gitDir := filepath.Join(filepath.Dir(filename), ".git")
+	if info, err := os.Stat(gitDir); err == nil && info.IsDir() {
+		return filepath.Join(gitDir, "voile-"+filepath.Base(filename)+".lock")
+	}
+
+	return filename + ".lock"
+}
+
+func (l fileLock) Lock() error {
+	ctx, cancel := context.WithTimeout(context.Background(), LockTimeout)
+	defer cancel()
+
+	locked, err := l.lock.TryLockContext(ctx, lockRetryDelay)
+	if err == nil && !locked {
+		err = errors.New("timed out")
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not lock %s (is another voile running?): %s", l.lock.Path(), err))
+	}
+
+	return nil
+}
+
+func (l fileLock) Unlock() error {
+	return l.lock.Unlock()
+}
+
+// Replaces the contents of a file such that a crash never leaves it partially written
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	// Temporary file must be on the same file system for the rename to be atomic
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	// Clean up if anything fails before the rename
+	tmpName := f.Name()
+	defer os.Remove(tmpName)
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, filename)
+}
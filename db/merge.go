@@ -0,0 +1,361 @@
+package db
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// An edit made on both sides of a merge that could not be combined, the
+// merged library keeps the most recently updated version
+type MergeConflict struct {
+	// Number of the bookmark in the merged library
+	Number int
+	Url    string
+	// Field that conflicted, "deleted" if one side deleted a bookmark the
+	// other changed or "duplicate" if each side gave a different bookmark the
+	// same URL, Theirs being the number of the one merged into this one
+	Field  string
+	Ours   string
+	Theirs string
+}
+
+type LibraryMergeResult struct {
+	Library   BookmarkLibrary
+	Conflicts []MergeConflict
+	// Bookmarks added by theirs that were given a new number, as their number
+	// was taken by ours
+	Renumbered map[int]int
+}
+
+// Three way merge of two versions of a library with their common ancestor.
+// Bookmarks are matched by number (or failing that URL) and merged field by
+// field, tags being merged as sets. Bookmarks added on either side are kept
+// and ours keep their numbers.
+func MergeLibraries(base, ours, theirs *BookmarkLibrary) LibraryMergeResult {
+	result := LibraryMergeResult{
+		Renumbered: make(map[int]int),
+	}
+
+	oursMatch := matchToBase(base, ours)
+	theirsMatch := matchToBase(base, theirs)
+
+	var merged []Bookmark
+
+	// Bookmarks that existed in the base
+	for i := range base.Bookmarks {
+		b := &(base.Bookmarks[i])
+		o, oOk := oursMatch.byBase[i]
+		t, tOk := theirsMatch.byBase[i]
+
+		switch {
+		case oOk && tOk:
+			bm, conflicts := mergeBookmark(b, o, t)
+			merged = append(merged, bm)
+			result.Conflicts = append(result.Conflicts, conflicts...)
+		case oOk:
+			// Theirs deleted it, which only stands if ours did not change it
+			if !bookmarksEqual(b, o) {
+				merged = append(merged, *o)
+				result.Conflicts = append(result.Conflicts, MergeConflict{o.Number, o.Url.String(), "deleted", "changed", "deleted"})
+			}
+		case tOk:
+			if !bookmarksEqual(b, t) {
+				merged = append(merged, *t)
+				result.Conflicts = append(result.Conflicts, MergeConflict{t.Number, t.Url.String(), "deleted", "deleted", "changed"})
+			}
+		}
+	}
+
+	// Bookmarks added by ours keep their numbers
+	merged = append(merged, oursMatch.added...)
+
+	result.Library.Bookmarks = merged
+	taken := make(map[int]bool)
+	for _, bm := range merged {
+		taken[bm.Number] = true
+	}
+
+	// Bookmarks added by theirs are merged with any added for the same URL,
+	// otherwise renumbered if their number is taken
+	for _, t := range theirsMatch.added {
+		t := t
+		if existing, err := result.Library.GetByUrl(t.Url.String()); err == nil {
+			empty := Bookmark{Number: existing.Number}
+			bm, conflicts := mergeBookmark(&empty, existing, &t)
+			*existing = bm
+			result.Conflicts = append(result.Conflicts, conflicts...)
+			continue
+		}
+
+		if taken[t.Number] {
+			number := maxNumber(&result.Library) + 1
+			result.Renumbered[t.Number] = number
+			t.Number = number
+		}
+		taken[t.Number] = true
+
+		result.Library.Bookmarks = append(result.Library.Bookmarks, t)
+	}
+
+	foldDuplicateUrls(&result)
+	sort.Sort(&(result.Library))
+
+	return result
+}
+
+// Merges bookmarks that ended up with the same URL, e.g. when each side
+// changed a different bookmark to it, into the first of them
+func foldDuplicateUrls(result *LibraryMergeResult) {
+	var folded []Bookmark
+	byUrl := make(map[string]int)
+
+	for _, bm := range result.Library.Bookmarks {
+		bm := bm
+		u := bm.Url.String()
+
+		i, ok := byUrl[u]
+		if !ok {
+			byUrl[u] = len(folded)
+			folded = append(folded, bm)
+			continue
+		}
+
+		existing := &(folded[i])
+		empty := Bookmark{Number: existing.Number}
+		merged, conflicts := mergeBookmark(&empty, existing, &bm)
+
+		result.Conflicts = append(result.Conflicts, MergeConflict{existing.Number, u, "duplicate", strconv.Itoa(existing.Number), strconv.Itoa(bm.Number)})
+		result.Conflicts = append(result.Conflicts, conflicts...)
+		*existing = merged
+	}
+
+	result.Library.Bookmarks = folded
+}
+
+// Three way merge of tag aliases, where both sides changed an alias theirs
+// is kept
+func MergeTagAliases(base, ours, theirs TagAliases) TagAliases {
+	merged := make(TagAliases)
+	for from, to := range ours {
+		merged[from] = to
+	}
+
+	for from, to := range theirs {
+		if base[from] != to {
+			merged[from] = to
+		}
+	}
+
+	// Aliases removed by theirs
+	for from, to := range base {
+		if _, ok := theirs[from]; !ok && ours[from] == to {
+			delete(merged, from)
+		}
+	}
+
+	return merged
+}
+
+type baseMatch struct {
+	// Bookmarks by the index of the base bookmark they descend from
+	byBase map[int]*Bookmark
+	added  []Bookmark
+}
+
+// Finds which bookmarks of a library descend from which in the base
+func matchToBase(base, side *BookmarkLibrary) baseMatch {
+	match := baseMatch{
+		byBase: make(map[int]*Bookmark),
+	}
+
+	baseByNumber := make(map[int]int)
+	baseByUrl := make(map[string]int)
+	for i, b := range base.Bookmarks {
+		baseByNumber[b.Number] = i
+		baseByUrl[b.Url.String()] = i
+	}
+
+	var unmatched []*Bookmark
+	for i := range side.Bookmarks {
+		bm := &(side.Bookmarks[i])
+
+		// Numbers are only reused if the highest numbered bookmark is
+		// deleted, so make sure it is the same bookmark
+		if j, ok := baseByNumber[bm.Number]; ok {
+			b := &(base.Bookmarks[j])
+			if b.WhenAdded.Equal(bm.WhenAdded) || b.Url.String() == bm.Url.String() {
+				match.byBase[j] = bm
+				continue
+			}
+		}
+
+		unmatched = append(unmatched, bm)
+	}
+
+	for _, bm := range unmatched {
+		if j, ok := baseByUrl[bm.Url.String()]; ok {
+			if _, taken := match.byBase[j]; !taken {
+				match.byBase[j] = bm
+				continue
+			}
+		}
+
+		match.added = append(match.added, *bm)
+	}
+
+	return match
+}
+
+// Merges the changes made to a bookmark on both sides
+func mergeBookmark(base, ours, theirs *Bookmark) (Bookmark, []MergeConflict) {
+	var conflicts []MergeConflict
+
+	// Where both sides changed a field the most recent edit wins
+	oursNewer := !theirs.LastUpdated.After(ours.LastUpdated)
+
+	bm := *ours
+	if !theirs.WhenAdded.IsZero() && theirs.WhenAdded.Before(bm.WhenAdded) {
+		bm.WhenAdded = theirs.WhenAdded
+	}
+
+	mergeField := func(field, b, o, t string) string {
+		v, conflict := mergeString(b, o, t, oursNewer)
+		if conflict {
+			conflicts = append(conflicts, MergeConflict{ours.Number, "", field, o, t})
+		}
+		return v
+	}
+
+	bm.Name = mergeField("title", base.Name, ours.Name, theirs.Name)
+	bm.Description = mergeField("description", base.Description, ours.Description, theirs.Description)
+
+	u := mergeField("url", base.Url.String(), ours.Url.String(), theirs.Url.String())
+	if u == theirs.Url.String() {
+		bm.Url = theirs.Url
+	}
+
+	bm.Tags = mergeTags(&base.Tags, &ours.Tags, &theirs.Tags)
+	bm.Aliases = unionStrings(ours.Aliases, theirs.Aliases)
+	for i, a := range bm.Aliases {
+		if a == bm.Url.String() {
+			bm.Aliases = append(bm.Aliases[:i], bm.Aliases[i+1:]...)
+			break
+		}
+	}
+
+	// Machine generated fields take the changed or newer version
+	if theirs.LinkCheck != nil && (ours.LinkCheck == nil || theirs.LinkCheck.Checked.After(ours.LinkCheck.Checked)) {
+		bm.LinkCheck = theirs.LinkCheck
+	}
+	if !jsonEqual(base.Metadata, theirs.Metadata) && (jsonEqual(base.Metadata, ours.Metadata) || !oursNewer) {
+		bm.Metadata = theirs.Metadata
+	}
+	bm.Snapshots = mergeSnapshots(ours.Snapshots, theirs.Snapshots)
+
+	if theirs.LastUpdated.After(bm.LastUpdated) {
+		bm.LastUpdated = theirs.LastUpdated
+	}
+
+	for i := range conflicts {
+		conflicts[i].Url = bm.Url.String()
+	}
+
+	return bm, conflicts
+}
+
+// Returns the merged value and whether both sides changed it differently
+func mergeString(base, ours, theirs string, oursNewer bool) (string, bool) {
+	switch {
+	case ours == theirs:
+		return ours, false
+	case ours == base:
+		return theirs, false
+	case theirs == base:
+		return ours, false
+	case oursNewer:
+		return ours, true
+	default:
+		return theirs, true
+	}
+}
+
+// Keeps tags added on either side unless removed on the other
+func mergeTags(base, ours, theirs *TagList) TagList {
+	if jsonEqual(ours, theirs) {
+		return *ours
+	}
+
+	var merged TagList
+	merged.Clear()
+
+	for _, t := range unionStrings(ours.Tags, theirs.Tags) {
+		// Lists edited by hand may not be sorted
+		removed := containsString(base.Tags, t) && (!containsString(ours.Tags, t) || !containsString(theirs.Tags, t))
+		if !removed {
+			merged.Append(t)
+		}
+	}
+
+	return merged
+}
+
+// Snapshots of both sides, oldest first
+func mergeSnapshots(ours, theirs []Snapshot) []Snapshot {
+	merged := append([]Snapshot{}, ours...)
+
+	for _, t := range theirs {
+		found := false
+		for i, o := range merged {
+			if o.File == t.File {
+				if t.Taken.After(o.Taken) {
+					merged[i].Taken = t.Taken
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, t)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Taken.Before(merged[j].Taken)
+	})
+
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+func unionStrings(a, b []string) []string {
+	var union []string
+	for _, s := range append(append([]string{}, a...), b...) {
+		if !containsString(union, s) {
+			union = append(union, s)
+		}
+	}
+	return union
+}
+
+func bookmarksEqual(a, b *Bookmark) bool {
+	return jsonEqual(a, b)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(rawA) == string(rawB)
+}
+
+func maxNumber(bmks *BookmarkLibrary) int {
+	max := 0
+	for _, bm := range bmks.Bookmarks {
+		if bm.Number > max {
+			max = bm.Number
+		}
+	}
+	return max
+}
@@ -0,0 +1,243 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func copyLibrary(t *testing.T, bmks *db.BookmarkLibrary) db.BookmarkLibrary {
+	raw, err := db.FormatJSONLibrary(bmks)
+	assert.Nil(t, err)
+
+	c, err := db.ParseJSONLibrary(raw)
+	assert.Nil(t, err)
+	return c
+}
+
+func TestMergeLibrariesUnchanged(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, 0, len(result.Conflicts))
+	assert.Equal(t, 0, len(result.Renumbered))
+	assert.Equal(t, base.Bookmarks, result.Library.Bookmarks)
+}
+
+func TestMergeLibrariesBothAdded(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	bm := ours.NewEntry()
+	bm.Url.Parse("https://example.com/ours")
+	bm = theirs.NewEntry()
+	bm.Url.Parse("https://example.com/theirs")
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, 0, len(result.Conflicts))
+	assert.Equal(t, map[int]int{4: 5}, result.Renumbered)
+	assert.Nil(t, result.Library.Verify())
+	assert.Equal(t, 5, result.Library.Len())
+
+	bm, _ = result.Library.GetByNumber(4)
+	assert.Equal(t, "https://example.com/ours", bm.Url.String())
+	bm, _ = result.Library.GetByNumber(5)
+	assert.Equal(t, "https://example.com/theirs", bm.Url.String())
+}
+
+func TestMergeLibrariesBothAddedSameUrl(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	bm := ours.NewEntry()
+	bm.Url.Parse("https://example.com")
+	bm.Name = ""
+	bm.Tags.Append("a")
+	bm = theirs.NewEntry()
+	bm.Url.Parse("https://example.com")
+	bm.Name = "Example"
+	bm.Tags.Append("b")
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, 0, len(result.Conflicts))
+	assert.Nil(t, result.Library.Verify())
+	assert.Equal(t, 4, result.Library.Len())
+
+	bm, _ = result.Library.GetByNumber(4)
+	assert.Equal(t, "Example", bm.Name)
+	assert.Equal(t, []string{"a", "b"}, bm.Tags.Tags)
+}
+
+func TestMergeLibrariesTags(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	ours.Bookmarks[0].Tags.Remove("news")
+	ours.Bookmarks[0].Tags.Append("a")
+	theirs.Bookmarks[0].Tags.Append("b")
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, 0, len(result.Conflicts))
+	assert.Equal(t, []string{"a", "b", "weather"}, result.Library.Bookmarks[0].Tags.Tags)
+}
+
+func TestMergeLibrariesFields(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	ours.Bookmarks[0].Name = "ours"
+	theirs.Bookmarks[0].Description = "theirs"
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, 0, len(result.Conflicts))
+	assert.Equal(t, "ours", result.Library.Bookmarks[0].Name)
+	assert.Equal(t, "theirs", result.Library.Bookmarks[0].Description)
+}
+
+func TestMergeLibrariesConflict(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	now := time.Now()
+	ours.Bookmarks[1].Name = "ours"
+	ours.Bookmarks[1].LastUpdated = now
+	theirs.Bookmarks[1].Name = "theirs"
+	theirs.Bookmarks[1].LastUpdated = now.Add(time.Minute)
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, []db.MergeConflict{
+		{Number: 2, Url: "https://facebook.com", Field: "title", Ours: "ours", Theirs: "theirs"},
+	}, result.Conflicts)
+	assert.Equal(t, "theirs", result.Library.Bookmarks[1].Name)
+	assert.True(t, result.Library.Bookmarks[1].LastUpdated.Equal(now.Add(time.Minute)))
+}
+
+func TestMergeLibrariesDeleted(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	// Deleted by ours, changed by theirs
+	ours.DeleteByNumber(1)
+	theirs.Bookmarks[0].Name = "changed"
+
+	// Deleted by theirs, unchanged by ours
+	theirs.DeleteByNumber(3)
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, []db.MergeConflict{
+		{Number: 1, Url: "https://github.com", Field: "deleted", Ours: "deleted", Theirs: "changed"},
+	}, result.Conflicts)
+	assert.Equal(t, 2, result.Library.Len())
+	assert.Equal(t, "changed", result.Library.Bookmarks[0].Name)
+	assert.Equal(t, 2, result.Library.Bookmarks[1].Number)
+}
+
+func TestMergeLibrariesReusedNumber(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	// Theirs deleted the last bookmark then added one with the same number
+	theirs.DeleteByNumber(3)
+	bm := theirs.NewEntry()
+	bm.Url.Parse("https://example.com")
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, 0, len(result.Conflicts))
+	assert.Equal(t, 3, result.Library.Len())
+
+	bm, _ = result.Library.GetByNumber(3)
+	assert.Equal(t, "https://example.com", bm.Url.String())
+}
+
+func TestMergeLibrariesAddedUrlMovedTo(t *testing.T) {
+	base := createTestLibrary()
+
+	// Theirs added the URL ours moved a bookmark to
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+	assert.Nil(t, ours.MoveUrl(2, "https://example.com"))
+	bm := theirs.NewEntry()
+	bm.Url.Parse("https://example.com")
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Nil(t, result.Library.Verify())
+	assert.Equal(t, 3, result.Library.Len())
+	bm, _ = result.Library.GetByNumber(2)
+	assert.Equal(t, "https://example.com", bm.Url.String())
+
+	// Ours added the URL theirs moved a bookmark to
+	ours = copyLibrary(t, &base)
+	theirs = copyLibrary(t, &base)
+	bm = ours.NewEntry()
+	bm.Url.Parse("https://example.com")
+	assert.Nil(t, theirs.MoveUrl(2, "https://example.com"))
+
+	result = db.MergeLibraries(&base, &ours, &theirs)
+	assert.Nil(t, result.Library.Verify())
+	assert.Equal(t, 3, result.Library.Len())
+	assert.Contains(t, result.Conflicts, db.MergeConflict{Number: 2, Url: "https://example.com", Field: "duplicate", Ours: "2", Theirs: "4"})
+
+	bm, _ = result.Library.GetByNumber(2)
+	assert.Equal(t, "https://example.com", bm.Url.String())
+	assert.Equal(t, []string{"software"}, bm.Tags.Tags)
+}
+
+func TestMergeLibrariesBothMovedToSameUrl(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	assert.Nil(t, ours.MoveUrl(1, "https://example.com"))
+	assert.Nil(t, theirs.MoveUrl(2, "https://example.com"))
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Nil(t, result.Library.Verify())
+	assert.Equal(t, 2, result.Library.Len())
+	assert.Equal(t, []db.MergeConflict{
+		{Number: 1, Url: "https://example.com", Field: "duplicate", Ours: "1", Theirs: "2"},
+		{Number: 1, Url: "https://example.com", Field: "title", Ours: "one", Theirs: "two"},
+	}, result.Conflicts)
+
+	bm, _ := result.Library.GetByNumber(1)
+	assert.Equal(t, "https://example.com", bm.Url.String())
+	assert.Equal(t, []string{"news", "software", "weather"}, bm.Tags.Tags)
+	assert.Equal(t, []string{"https://github.com", "https://facebook.com"}, bm.Aliases)
+}
+
+func TestMergeTagAliases(t *testing.T) {
+	base := db.TagAliases{"golang": "go", "js": "javascript", "py": "python"}
+	ours := db.TagAliases{"golang": "go", "js": "ecmascript", "py": "python", "rs": "rust"}
+	theirs := db.TagAliases{"golang": "go", "js": "javascript", "cpp": "c++"}
+
+	assert.Equal(t, db.TagAliases{
+		"golang": "go",
+		"js":     "ecmascript",
+		"rs":     "rust",
+		"cpp":    "c++",
+	}, db.MergeTagAliases(base, ours, theirs))
+}
+
+func TestMergeLibrariesUnsortedTags(t *testing.T) {
+	base := createTestLibrary()
+	ours := copyLibrary(t, &base)
+	theirs := copyLibrary(t, &base)
+
+	base.Bookmarks[0].Tags.Tags = []string{"weather", "news"}
+	ours.Bookmarks[0].Tags.Tags = []string{"weather", "news", "a"}
+	theirs.Bookmarks[0].Tags.Tags = []string{"weather", "news", "b"}
+
+	result := db.MergeLibraries(&base, &ours, &theirs)
+	assert.Equal(t, []string{"a", "b", "news", "weather"}, result.Library.Bookmarks[0].Tags.Tags)
+}
@@ -0,0 +1,18 @@
+package db
+
+import (
+	"time"
+)
+
+// Details about a bookmarked page taken from the page itself
+type Metadata struct {
+	Author string `json:"author,omitempty"`
+	// Nil if the page does not say
+	Published *time.Time `json:"published,omitempty"`
+	Favicon   string     `json:"favicon,omitempty"`
+}
+
+// Libraries saved before the date was optional store unknown dates as zero
+func (md *Metadata) HasPublished() bool {
+	return md.Published != nil && !md.Published.IsZero()
+}
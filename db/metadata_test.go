@@ -0,0 +1,38 @@
+package db_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func TestMetadataJSONUnknownPublished(t *testing.T) {
+	raw, err := json.Marshal(&db.Metadata{Author: "Someone"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"author":"Someone"}`, string(raw))
+
+	published := time.Date(2019, time.May, 6, 0, 0, 0, 0, time.UTC)
+	raw, err = json.Marshal(&db.Metadata{Published: &published})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"published":"2019-05-06T00:00:00Z"}`, string(raw))
+
+	var md db.Metadata
+	assert.Nil(t, json.Unmarshal(raw, &md))
+	assert.True(t, published.Equal(*md.Published))
+}
+
+func TestMetadataHasPublished(t *testing.T) {
+	var md db.Metadata
+	assert.False(t, md.HasPublished())
+
+	// As stored before the date was optional
+	assert.Nil(t, json.Unmarshal([]byte(`{"published":"0001-01-01T00:00:00Z"}`), &md))
+	assert.False(t, md.HasPublished())
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"published":"2019-05-06T00:00:00Z"}`), &md))
+	assert.True(t, md.HasPublished())
+}
@@ -0,0 +1,428 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const QueryDateFormat = "2006-01-02"
+
+type Predicate func(bm *Bookmark) bool
+
+type QueryError struct {
+	Query    string
+	Position int
+	Message  string
+}
+
+func (e *QueryError) Error() string {
+	// Point at the offending token underneath the query
+	return fmt.Sprintf("%s at position %d\n  %s\n  %s^",
+		e.Message, e.Position+1, e.Query, strings.Repeat(" ", e.Position))
+}
+
+type queryTokenType int
+
+const (
+	queryTokenWord queryTokenType = iota
+	queryTokenOpen
+	queryTokenClose
+	queryTokenAnd
+	queryTokenOr
+	queryTokenNot
+	queryTokenEnd
+)
+
+type queryToken struct {
+	Type     queryTokenType
+	Text     string
+	Position int
+
+	// Index in Text of the colon separating the field name, -1 if no field
+	FieldEnd int
+}
+
+type queryParser struct {
+	query  string
+	tokens []queryToken
+	pos    int
+}
+
+// Parses a boolean query such as:
+//
+//	tag:news AND (title:bbc OR url:*.co.uk) AND NOT tag:sport added:>2024-01-01
+//
+// Adjacent terms are implicitly ANDed together, terms without a field match
+// against the name, URL, description or any tag.
+func ParseQuery(query string) (Predicate, error) {
+	tokens, err := tokeniseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := queryParser{
+		query:  query,
+		tokens: tokens,
+	}
+
+	if p.peek().Type == queryTokenEnd {
+		return nil, p.errorAt(p.peek(), "Empty query")
+	}
+
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.Type != queryTokenEnd {
+		return nil, p.errorAt(t, fmt.Sprintf("Unexpected '%s'", t.Text))
+	}
+
+	return pred, nil
+}
+
+func tokeniseQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+
+	// Positions are reported in runes so they line up when printed
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{queryTokenOpen, "(", i, -1})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{queryTokenClose, ")", i, -1})
+			i++
+		default:
+			start := i
+			fieldEnd := -1
+			var word strings.Builder
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				if runes[i] == '"' {
+					// Quoted section may contain spaces and parentheses
+					quoteStart := i
+					i++
+					for i < len(runes) && runes[i] != '"' {
+						word.WriteRune(runes[i])
+						i++
+					}
+					if i == len(runes) {
+						return nil, &QueryError{query, quoteStart, "Unterminated quote"}
+					}
+				} else {
+					if runes[i] == ':' && fieldEnd < 0 {
+						fieldEnd = word.Len()
+					}
+					word.WriteRune(runes[i])
+				}
+				i++
+			}
+
+			text := string(runes[start:i])
+			t := queryToken{queryTokenWord, word.String(), start, fieldEnd}
+			switch text {
+			case "AND":
+				t.Type = queryTokenAnd
+			case "OR":
+				t.Type = queryTokenOr
+			case "NOT":
+				t.Type = queryTokenNot
+			}
+			tokens = append(tokens, t)
+		}
+	}
+
+	tokens = append(tokens, queryToken{queryTokenEnd, "end of query", len(runes), -1})
+	return tokens, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.pos]
+	if t.Type != queryTokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) errorAt(t queryToken, message string) error {
+	return &QueryError{p.query, t.Position, message}
+}
+
+func (p *queryParser) parseOr() (Predicate, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().Type == queryTokenOr {
+		p.next()
+
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = orPredicate(lhs, rhs)
+	}
+
+	return lhs, nil
+}
+
+func (p *queryParser) parseAnd() (Predicate, error) {
+	lhs, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().Type {
+		case queryTokenAnd:
+			p.next()
+		case queryTokenWord, queryTokenNot, queryTokenOpen:
+			// Implicit AND
+		default:
+			return lhs, nil
+		}
+
+		rhs, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = andPredicate(lhs, rhs)
+	}
+}
+
+func (p *queryParser) parseNot() (Predicate, error) {
+	if p.peek().Type == queryTokenNot {
+		p.next()
+
+		pred, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(bm *Bookmark) bool { return !pred(bm) }, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (Predicate, error) {
+	t := p.next()
+
+	switch t.Type {
+	case queryTokenOpen:
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if c := p.next(); c.Type != queryTokenClose {
+			return nil, p.errorAt(c, fmt.Sprintf("Expected ')' but found '%s'", c.Text))
+		}
+
+		return pred, nil
+	case queryTokenWord:
+		return p.parseTerm(t)
+	case queryTokenEnd:
+		return nil, p.errorAt(t, "Unexpected end of query")
+	default:
+		return nil, p.errorAt(t, fmt.Sprintf("Unexpected '%s'", t.Text))
+	}
+}
+
+// Words with any other prefix, such as URLs, are searched for as they are
+var queryFields = map[string]bool{
+	"tag": true, "tags": true,
+	"title": true, "name": true,
+	"url": true, "uri": true,
+	"desc": true, "description": true,
+	"added": true, "updated": true,
+	"number": true, "n": true,
+}
+
+func (p *queryParser) parseTerm(t queryToken) (Predicate, error) {
+	field := ""
+	value := t.Text
+	if t.FieldEnd > 0 && queryFields[strings.ToLower(t.Text[:t.FieldEnd])] {
+		field = strings.ToLower(t.Text[:t.FieldEnd])
+		value = t.Text[t.FieldEnd+1:]
+	}
+
+	if len(value) == 0 {
+		return nil, p.errorAt(t, "Missing value")
+	}
+
+	switch field {
+	case "tag", "tags":
+		if isGlob(value) {
+			re := globToRegexp(value)
+			return func(bm *Bookmark) bool {
+				for _, tag := range bm.Tags.Tags {
+					if re.MatchString(tag) {
+						return true
+					}
+				}
+				return false
+			}, nil
+		}
+		return func(bm *Bookmark) bool { return bm.Tags.HasTag(value) }, nil
+	case "title", "name":
+		return stringFieldPredicate(value, func(bm *Bookmark) string { return bm.Name }), nil
+	case "url", "uri":
+		return urlFieldPredicate(value), nil
+	case "desc", "description":
+		return stringFieldPredicate(value, func(bm *Bookmark) string { return bm.Description }), nil
+	case "added":
+		return p.dateFieldPredicate(t, value, func(bm *Bookmark) time.Time { return bm.WhenAdded })
+	case "updated":
+		return p.dateFieldPredicate(t, value, func(bm *Bookmark) time.Time { return bm.LastUpdated })
+	case "number", "n":
+		return p.numberFieldPredicate(t, value)
+	default:
+		return anyFieldPredicate(value), nil
+	}
+}
+
+func (p *queryParser) dateFieldPredicate(t queryToken, value string, get func(*Bookmark) time.Time) (Predicate, error) {
+	op, value := splitComparison(value)
+
+	day, err := time.ParseInLocation(QueryDateFormat, value, time.Local)
+	if err != nil {
+		return nil, p.errorAt(t, fmt.Sprintf("Invalid date '%s' (expected YYYY-MM-DD)", value))
+	}
+	nextDay := day.AddDate(0, 0, 1)
+
+	// Comparisons are against whole days
+	switch op {
+	case ">":
+		return func(bm *Bookmark) bool { return !get(bm).Before(nextDay) }, nil
+	case ">=":
+		return func(bm *Bookmark) bool { return !get(bm).Before(day) }, nil
+	case "<":
+		return func(bm *Bookmark) bool { return get(bm).Before(day) }, nil
+	case "<=":
+		return func(bm *Bookmark) bool { return get(bm).Before(nextDay) }, nil
+	default:
+		return func(bm *Bookmark) bool {
+			return !get(bm).Before(day) && get(bm).Before(nextDay)
+		}, nil
+	}
+}
+
+func (p *queryParser) numberFieldPredicate(t queryToken, value string) (Predicate, error) {
+	op, value := splitComparison(value)
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, p.errorAt(t, fmt.Sprintf("Invalid number '%s'", value))
+	}
+
+	switch op {
+	case ">":
+		return func(bm *Bookmark) bool { return bm.Number > number }, nil
+	case ">=":
+		return func(bm *Bookmark) bool { return bm.Number >= number }, nil
+	case "<":
+		return func(bm *Bookmark) bool { return bm.Number < number }, nil
+	case "<=":
+		return func(bm *Bookmark) bool { return bm.Number <= number }, nil
+	default:
+		return func(bm *Bookmark) bool { return bm.Number == number }, nil
+	}
+}
+
+func splitComparison(value string) (string, string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+	return "=", value
+}
+
+func andPredicate(lhs, rhs Predicate) Predicate {
+	return func(bm *Bookmark) bool { return lhs(bm) && rhs(bm) }
+}
+
+func orPredicate(lhs, rhs Predicate) Predicate {
+	return func(bm *Bookmark) bool { return lhs(bm) || rhs(bm) }
+}
+
+func anyFieldPredicate(value string) Predicate {
+	fields := []Predicate{
+		stringFieldPredicate(value, func(bm *Bookmark) string { return bm.Name }),
+		stringFieldPredicate(value, func(bm *Bookmark) string { return bm.Url.String() }),
+		stringFieldPredicate(value, func(bm *Bookmark) string { return bm.Description }),
+		func(bm *Bookmark) bool { return bm.Tags.HasTag(value) },
+	}
+
+	return func(bm *Bookmark) bool {
+		for _, f := range fields {
+			if f(bm) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Globs must match the entire field, anything else is a substring search
+func stringFieldPredicate(value string, get func(*Bookmark) string) Predicate {
+	if isGlob(value) {
+		re := globToRegexp(value)
+		return func(bm *Bookmark) bool { return re.MatchString(get(bm)) }
+	}
+
+	return func(bm *Bookmark) bool { return subStringMatches(value, get(bm)) }
+}
+
+// URL globs may match either the whole URL or just the host, so *.co.uk
+// matches any page on those sites
+func urlFieldPredicate(value string) Predicate {
+	if isGlob(value) {
+		re := globToRegexp(value)
+		return func(bm *Bookmark) bool {
+			return re.MatchString(bm.Url.String()) || re.MatchString(bm.Url.Url.Hostname())
+		}
+	}
+
+	return stringFieldPredicate(value, func(bm *Bookmark) string { return bm.Url.String() })
+}
+
+func isGlob(value string) bool {
+	return strings.ContainsAny(value, "*?")
+}
+
+func globToRegexp(glob string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String())
+}
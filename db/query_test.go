@@ -0,0 +1,157 @@
+package db_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func createTestQueryLibrary() db.BookmarkLibrary {
+	bmks := createTestLibrary()
+	bmks.Bookmarks[0].WhenAdded = time.Date(2023, time.June, 1, 12, 0, 0, 0, time.Local)
+	bmks.Bookmarks[1].WhenAdded = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.Local)
+	bmks.Bookmarks[2].WhenAdded = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.Local)
+	bmks.Bookmarks[2].Description = "British Broadcasting Corporation"
+	return bmks
+}
+
+func queryNumbers(t *testing.T, query string) []int {
+	pred, err := db.ParseQuery(query)
+	assert.Nil(t, err)
+	if err != nil {
+		return nil
+	}
+
+	numbers := []int{}
+	bmks := createTestQueryLibrary()
+	for i := range bmks.Bookmarks {
+		if pred(&bmks.Bookmarks[i]) {
+			numbers = append(numbers, bmks.Bookmarks[i].Number)
+		}
+	}
+	return numbers
+}
+
+func TestParseQueryFields(t *testing.T) {
+	assert.Equal(t, []int{1, 3}, queryNumbers(t, "tag:news"))
+	assert.Equal(t, []int{2}, queryNumbers(t, "title:TWO"))
+	assert.Equal(t, []int{3}, queryNumbers(t, "url:bbc"))
+	assert.Equal(t, []int{3}, queryNumbers(t, "desc:broadcasting"))
+	assert.Equal(t, []int{2}, queryNumbers(t, "number:2"))
+	assert.Equal(t, []int{2, 3}, queryNumbers(t, "n:>1"))
+}
+
+func TestParseQueryTagHierarchy(t *testing.T) {
+	pred, err := db.ParseQuery("tag:lang")
+	assert.Nil(t, err)
+
+	bm := db.Bookmark{Tags: db.TagList{Tags: []string{"lang/go"}}}
+	assert.True(t, pred(&bm))
+
+	bm = db.Bookmark{Tags: db.TagList{Tags: []string{"language"}}}
+	assert.False(t, pred(&bm))
+}
+
+func TestParseQueryBareTerm(t *testing.T) {
+	assert.Equal(t, []int{1}, queryNumbers(t, "github"))
+	assert.Equal(t, []int{2, 3}, queryNumbers(t, "software"))
+	assert.Equal(t, []int{}, queryNumbers(t, "nothing"))
+}
+
+func TestParseQueryUnknownField(t *testing.T) {
+	// Searched for as it is, e.g. a pasted URL
+	assert.Equal(t, []int{1}, queryNumbers(t, "https://github.com"))
+	assert.Equal(t, []int{}, queryNumbers(t, "bogus:x"))
+
+	pred, err := db.ParseQuery("see:also")
+	assert.Nil(t, err)
+	bm := db.Bookmark{Description: "See: also this"}
+	assert.False(t, pred(&bm))
+	bm.Description = "see:also this"
+	assert.True(t, pred(&bm))
+}
+
+func TestParseQueryGlob(t *testing.T) {
+	assert.Equal(t, []int{3}, queryNumbers(t, "url:*.co.uk"))
+	assert.Equal(t, []int{}, queryNumbers(t, "url:*.co"))
+	assert.Equal(t, []int{3}, queryNumbers(t, "url:https://*.uk"))
+	assert.Equal(t, []int{1}, queryNumbers(t, "tag:wea*"))
+	assert.Equal(t, []int{2}, queryNumbers(t, "title:t?o"))
+}
+
+func TestParseQueryUrlGlobMatchesHost(t *testing.T) {
+	pred, err := db.ParseQuery("url:*.co.uk")
+	assert.Nil(t, err)
+
+	var bm db.Bookmark
+	bm.Url.Parse("https://www.bbc.co.uk/news")
+	assert.True(t, pred(&bm))
+	bm.Url.Parse("https://www.bbc.co.uk.example.com/news")
+	assert.False(t, pred(&bm))
+}
+
+func TestParseQueryBoolean(t *testing.T) {
+	assert.Equal(t, []int{3}, queryNumbers(t, "tag:news AND tag:software"))
+	assert.Equal(t, []int{3}, queryNumbers(t, "tag:news tag:software"))
+	assert.Equal(t, []int{1, 2, 3}, queryNumbers(t, "tag:news OR tag:software"))
+	assert.Equal(t, []int{1}, queryNumbers(t, "tag:news AND NOT tag:software"))
+	assert.Equal(t, []int{1, 3}, queryNumbers(t, "NOT NOT tag:news"))
+	assert.Equal(t, []int{3}, queryNumbers(t, "tag:news AND (title:three OR url:facebook)"))
+	assert.Equal(t, []int{2, 3}, queryNumbers(t, "title:two OR title:three AND tag:news"))
+}
+
+func TestParseQueryDates(t *testing.T) {
+	assert.Equal(t, []int{3}, queryNumbers(t, "added:>2024-01-01"))
+	assert.Equal(t, []int{2, 3}, queryNumbers(t, "added:>=2024-01-01"))
+	assert.Equal(t, []int{1}, queryNumbers(t, "added:<2024-01-01"))
+	assert.Equal(t, []int{1, 2}, queryNumbers(t, "added:<=2024-01-01"))
+	assert.Equal(t, []int{2}, queryNumbers(t, "added:2024-01-01"))
+}
+
+func TestParseQueryQuoted(t *testing.T) {
+	assert.Equal(t, []int{3}, queryNumbers(t, `desc:"broadcasting corp"`))
+	assert.Equal(t, []int{3}, queryNumbers(t, `"British Broadcasting"`))
+}
+
+func TestParseQueryQuotedColon(t *testing.T) {
+	pred, err := db.ParseQuery(`"https://bbc"`)
+	assert.Nil(t, err)
+
+	bm := db.Bookmark{Url: db.Url{url.URL{Scheme: "https", Host: "bbc.co.uk"}}}
+	assert.True(t, pred(&bm))
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	cases := []struct {
+		Query    string
+		Position int
+		Message  string
+	}{
+		{"", 0, "Empty query"},
+		{"tag:news AND", 12, "Unexpected end of query"},
+		{"(tag:news", 9, "Expected ')' but found 'end of query'"},
+		{"tag:news )", 9, "Unexpected ')'"},
+		{"tag:news OR OR tag:x", 12, "Unexpected 'OR'"},
+		{"tag: x", 0, "Missing value"},
+		{"added:>yesterday", 0, "Invalid date 'yesterday' (expected YYYY-MM-DD)"},
+		{`title:"unterminated`, 6, "Unterminated quote"},
+	}
+
+	for _, c := range cases {
+		_, err := db.ParseQuery(c.Query)
+		if assert.IsType(t, &db.QueryError{}, err, c.Query) {
+			qe := err.(*db.QueryError)
+			assert.Equal(t, c.Position, qe.Position, c.Query)
+			assert.Equal(t, c.Message, qe.Message, c.Query)
+		}
+	}
+}
+
+func TestQueryErrorString(t *testing.T) {
+	_, err := db.ParseQuery("tag:news )")
+	assert.Equal(t, "Unexpected ')' at position 10\n  tag:news )\n           ^", err.Error())
+}
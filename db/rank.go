@@ -0,0 +1,155 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Relative importance of a match in each field
+const (
+	nameRankWeight        = 4.0
+	tagsRankWeight        = 3.0
+	urlRankWeight         = 2.0
+	descriptionRankWeight = 1.0
+)
+
+// Score of a single word matching a term, by type of match
+const (
+	exactRankScore     = 1.0
+	prefixRankScore    = 0.75
+	substringRankScore = 0.5
+	fuzzyRankScore     = 0.4
+)
+
+type ScoredBookmark struct {
+	Bookmark *Bookmark
+	Score    float64
+}
+
+// Scores how well the bookmark matches a set of free text terms, zero meaning
+// no match at all
+func (bm *Bookmark) Score(terms []string) float64 {
+	fields := []struct {
+		Words  []string
+		Weight float64
+	}{
+		{splitRankWords(bm.Name), nameRankWeight},
+		{bm.Tags.Tags, tagsRankWeight},
+		{splitRankWords(bm.Url.String()), urlRankWeight},
+		{splitRankWords(bm.Description), descriptionRankWeight},
+	}
+
+	score := 0.0
+	for _, term := range terms {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if len(term) == 0 {
+			continue
+		}
+
+		// Matches in several fields rank higher than a match in one
+		for _, f := range fields {
+			best := 0.0
+			for _, w := range f.Words {
+				if s := scoreRankWord(term, strings.ToLower(w)); s > best {
+					best = s
+				}
+			}
+			score += best * f.Weight
+		}
+	}
+
+	return score
+}
+
+// Returns bookmarks that match any of the terms, best match first
+func (bmks *BookmarkLibrary) Rank(terms []string) []ScoredBookmark {
+	var results []ScoredBookmark
+	for i := range bmks.Bookmarks {
+		bm := &(bmks.Bookmarks[i])
+		if s := bm.Score(terms); s > 0 {
+			results = append(results, ScoredBookmark{bm, s})
+		}
+	}
+
+	// Stable so that equal scores stay in library order
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
+
+func splitRankWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func scoreRankWord(term, word string) float64 {
+	switch {
+	case word == term:
+		return exactRankScore
+	case strings.HasPrefix(word, term):
+		return prefixRankScore
+	case strings.Contains(word, term):
+		return substringRankScore
+	}
+
+	// Allow more typos in longer terms, none in very short ones
+	termLen := len([]rune(term))
+	maxTypos := 0
+	if termLen >= 8 {
+		maxTypos = 2
+	} else if termLen >= 4 {
+		maxTypos = 1
+	}
+
+	if maxTypos > 0 {
+		if d := editDistance(term, word); d <= maxTypos {
+			return fuzzyRankScore / float64(d)
+		}
+	}
+
+	return 0
+}
+
+// Optimal string alignment distance, i.e. Levenshtein distance that also
+// counts swapping two adjacent characters as one edit
+func editDistance(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = minInt(d[i-1][j]+1, minInt(d[i][j-1]+1, d[i-1][j-1]+cost))
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
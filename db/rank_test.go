@@ -0,0 +1,64 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func rankedNumbers(bmks *db.BookmarkLibrary, terms ...string) []int {
+	numbers := []int{}
+	for _, r := range bmks.Rank(terms) {
+		numbers = append(numbers, r.Bookmark.Number)
+	}
+	return numbers
+}
+
+func TestBookmarkScoreNoMatch(t *testing.T) {
+	assert.Equal(t, 0.0, testBookmark.Score([]string{"nothing"}))
+	assert.Equal(t, 0.0, testBookmark.Score([]string{}))
+	assert.Equal(t, 0.0, testBookmark.Score([]string{" "}))
+}
+
+func TestBookmarkScoreMatchTypes(t *testing.T) {
+	exact := testBookmark.Score([]string{"weather"})
+	prefix := testBookmark.Score([]string{"weath"})
+	fuzzy := testBookmark.Score([]string{"waether"})
+
+	assert.True(t, exact > prefix)
+	assert.True(t, prefix > fuzzy)
+	assert.True(t, fuzzy > 0)
+}
+
+func TestBookmarkScoreShortTermsNotFuzzy(t *testing.T) {
+	assert.Equal(t, 0.0, testBookmark.Score([]string{"bcb"}))
+}
+
+func TestBookmarkLibraryRankFieldWeights(t *testing.T) {
+	bmks := db.BookmarkLibrary{
+		Bookmarks: []db.Bookmark{
+			{Number: 1, Name: "Something", Description: "golang tutorial"},
+			{Number: 2, Name: "Golang"},
+			{Number: 3, Name: "Other", Tags: db.TagList{Tags: []string{"golang"}}},
+			{Number: 4, Name: "Unrelated"},
+		},
+	}
+
+	assert.Equal(t, []int{2, 3, 1}, rankedNumbers(&bmks, "golang"))
+}
+
+func TestBookmarkLibraryRankTypo(t *testing.T) {
+	bmks := createTestLibrary()
+
+	assert.Equal(t, []int{1}, rankedNumbers(&bmks, "githbu"))
+	assert.Equal(t, []int{2, 3}, rankedNumbers(&bmks, "sotfware"))
+}
+
+func TestBookmarkLibraryRankMultipleTerms(t *testing.T) {
+	bmks := createTestLibrary()
+
+	// Bookmarks matching more terms come first, ties keep library order
+	assert.Equal(t, []int{3, 1, 2}, rankedNumbers(&bmks, "news", "software"))
+}
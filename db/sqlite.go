@@ -0,0 +1,491 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	_ "modernc.org/sqlite"
+)
+
+// Applied in order, the database's user_version records how many have been
+var sqliteMigrations = []string{
+	`
+CREATE TABLE IF NOT EXISTS bookmarks (
+	number INTEGER PRIMARY KEY,
+	uri TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL,
+	tags TEXT NOT NULL,
+	when_added TEXT NOT NULL,
+	last_updated TEXT NOT NULL
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS bookmarks_fts USING fts5(
+	title, uri, description, tags,
+	tokenize = 'trigram'
+);
+`,
+	// Entire bookmark as JSON so that every field is kept, the other columns
+	// are only used for constraints and searching
+	`ALTER TABLE bookmarks ADD COLUMN data TEXT NOT NULL DEFAULT ''`,
+	// Index previous URLs and folded text, rebuilt after migrating
+	`
+DROP TABLE IF EXISTS bookmarks_fts;
+CREATE VIRTUAL TABLE bookmarks_fts USING fts5(
+	title, uri, description, tags, aliases,
+	tokenize = 'trigram'
+);
+`,
+}
+
+// Databases older than this version need their index rebuilt
+const sqliteIndexVersion = 3
+
+const sqliteSelectBookmarks = `SELECT number, uri, title, description, tags, when_added, last_updated, data FROM bookmarks`
+
+// The trigram tokenizer can not match anything shorter than this
+const sqliteMinSearchTermLength = 3
+
+type SQLiteStore struct {
+	Filename string
+	db       *sql.DB
+	lock     fileLock
+
+	// Database version when last loaded or saved, changes when another
+	// connection modifies the database
+	loaded        bool
+	loadedVersion int64
+}
+
+func NewSQLiteStore(filename string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// data_version is only meaningful when queried on the same connection
+	db.SetMaxOpenConns(1)
+
+	version, err := migrateSQLiteDatabase(db)
+	if err == nil && version < sqliteIndexVersion {
+		err = rebuildSQLiteIndex(db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{
+		Filename: filename,
+		db:       db,
+		lock:     newFileLock(filename),
+	}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Path() string {
+	return s.Filename
+}
+
+func (s *SQLiteStore) Load() (BookmarkLibrary, error) {
+	var bmks BookmarkLibrary
+
+	version, err := s.dataVersion()
+	if err != nil {
+		return bmks, err
+	}
+
+	rows, err := s.db.Query(sqliteSelectBookmarks)
+	if err != nil {
+		return bmks, err
+	}
+
+	bmks.Bookmarks, err = scanSQLiteBookmarks(rows)
+	if err != nil {
+		return bmks, err
+	}
+
+	s.loaded = true
+	s.loadedVersion = version
+
+	// Validate the loaded data
+	err = bmks.Verify()
+	if err != nil {
+		return bmks, err
+	}
+
+	sort.Sort(&bmks)
+
+	return bmks, nil
+}
+
+func (s *SQLiteStore) Save(bmks *BookmarkLibrary) error {
+	// Validate the bookmarks before saving
+	err := bmks.Verify()
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Make sure nothing else wrote to the database since it was loaded
+	if s.loaded {
+		var version int64
+		err = tx.QueryRow("PRAGMA data_version").Scan(&version)
+		if err == nil && version != s.loadedVersion {
+			err = ErrLibraryChanged
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = saveSQLiteBookmarks(tx, bmks)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	// Own commits do not change the version seen by this connection
+	s.loaded = true
+	s.loadedVersion, err = s.dataVersion()
+	return err
+}
+
+func (s *SQLiteStore) Lock() error {
+	return s.lock.Lock()
+}
+
+func (s *SQLiteStore) Unlock() error {
+	return s.lock.Unlock()
+}
+
+func (s *SQLiteStore) Search(terms SearchTerms) (BookmarkLibrary, error) {
+	var bmks BookmarkLibrary
+
+	// Build full text query from the terms the index is able to match
+	var clauses []string
+	addClause := func(column, term string) {
+		term = foldSQLiteSearchText(term)
+		if utf8.RuneCountInString(term) >= sqliteMinSearchTermLength && isASCII(term) {
+			clauses = append(clauses, fmt.Sprintf("%s : %s", column, quoteFTSString(term)))
+		}
+	}
+	addClause("title", terms.Name)
+	addClause("{uri aliases}", terms.Url)
+	addClause("description", terms.Description)
+	for _, t := range terms.Tags {
+		addClause("tags", t)
+	}
+
+	var rows *sql.Rows
+	var err error
+	if len(clauses) == 0 {
+		rows, err = s.db.Query(sqliteSelectBookmarks)
+	} else {
+		rows, err = s.db.Query(
+			sqliteSelectBookmarks+` WHERE number IN (SELECT rowid FROM bookmarks_fts WHERE bookmarks_fts MATCH ?)`,
+			strings.Join(clauses, " AND "))
+	}
+	if err != nil {
+		return bmks, err
+	}
+
+	bmks.Bookmarks, err = scanSQLiteBookmarks(rows)
+	if err != nil {
+		return bmks, err
+	}
+
+	sort.Sort(&bmks)
+
+	return bmks, nil
+}
+
+func (s *SQLiteStore) dataVersion() (int64, error) {
+	var version int64
+	err := s.db.QueryRow("PRAGMA data_version").Scan(&version)
+	return version, err
+}
+
+// Returns the version the database had before migrating
+func migrateSQLiteDatabase(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return version, err
+	}
+
+	from := version
+	for ; version < len(sqliteMigrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return from, err
+		}
+
+		_, err = tx.Exec(sqliteMigrations[version])
+		if err == nil {
+			_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1))
+		}
+		if err != nil {
+			tx.Rollback()
+			return from, err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return from, err
+		}
+	}
+
+	return from, nil
+}
+
+func rebuildSQLiteIndex(db *sql.DB) error {
+	rows, err := db.Query(sqliteSelectBookmarks)
+	if err != nil {
+		return err
+	}
+
+	bookmarks, err := scanSQLiteBookmarks(rows)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		if _, err := tx.Exec("DELETE FROM bookmarks_fts"); err != nil {
+			return err
+		}
+
+		insertIndex, err := tx.Prepare(sqliteInsertIndex)
+		if err != nil {
+			return err
+		}
+		defer insertIndex.Close()
+
+		for i := range bookmarks {
+			if err = indexSQLiteBookmark(insertIndex, &(bookmarks[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const sqliteInsertIndex = `INSERT INTO bookmarks_fts (rowid, title, uri, description, tags, aliases) VALUES (?, ?, ?, ?, ?, ?)`
+
+// Only writes the bookmarks that were added, changed or deleted
+func saveSQLiteBookmarks(tx *sql.Tx, bmks *BookmarkLibrary) error {
+	// Bookmarks as last saved
+	stored := make(map[int]string)
+	rows, err := tx.Query("SELECT number, data FROM bookmarks")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var number int
+		var data string
+		if err = rows.Scan(&number, &data); err != nil {
+			rows.Close()
+			return err
+		}
+		stored[number] = data
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// Changed rows are removed before inserting so that URLs moved between
+	// bookmarks never clash
+	var changed []*Bookmark
+	var changedData []string
+	var removed []int
+	current := make(map[int]bool)
+	for i := range bmks.Bookmarks {
+		bm := &(bmks.Bookmarks[i])
+		current[bm.Number] = true
+
+		data, err := json.Marshal(bm)
+		if err != nil {
+			return err
+		}
+
+		previous, ok := stored[bm.Number]
+		if ok && previous == string(data) {
+			continue
+		}
+		if ok {
+			removed = append(removed, bm.Number)
+		}
+		changed = append(changed, bm)
+		changedData = append(changedData, string(data))
+	}
+	for number := range stored {
+		if !current[number] {
+			removed = append(removed, number)
+		}
+	}
+
+	deleteBookmark, err := tx.Prepare(`DELETE FROM bookmarks WHERE number = ?`)
+	if err != nil {
+		return err
+	}
+	defer deleteBookmark.Close()
+
+	deleteIndex, err := tx.Prepare(`DELETE FROM bookmarks_fts WHERE rowid = ?`)
+	if err != nil {
+		return err
+	}
+	defer deleteIndex.Close()
+
+	for _, number := range removed {
+		if _, err = deleteBookmark.Exec(number); err != nil {
+			return err
+		}
+		if _, err = deleteIndex.Exec(number); err != nil {
+			return err
+		}
+	}
+
+	insertBookmark, err := tx.Prepare(`INSERT INTO bookmarks (number, uri, title, description, tags, when_added, last_updated, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertBookmark.Close()
+
+	insertIndex, err := tx.Prepare(sqliteInsertIndex)
+	if err != nil {
+		return err
+	}
+	defer insertIndex.Close()
+
+	for i, bm := range changed {
+		tags, err := json.Marshal(bm.Tags)
+		if err != nil {
+			return err
+		}
+
+		_, err = insertBookmark.Exec(
+			bm.Number, bm.Url.String(), bm.Name, bm.Description, string(tags),
+			bm.WhenAdded.Format(time.RFC3339Nano), bm.LastUpdated.Format(time.RFC3339Nano),
+			changedData[i])
+		if err != nil {
+			return err
+		}
+
+		if err = indexSQLiteBookmark(insertIndex, bm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func indexSQLiteBookmark(insertIndex *sql.Stmt, bm *Bookmark) error {
+	_, err := insertIndex.Exec(
+		bm.Number,
+		foldSQLiteSearchText(bm.Name),
+		foldSQLiteSearchText(bm.Url.String()),
+		foldSQLiteSearchText(bm.Description),
+		foldSQLiteSearchText(bm.Tags.MultilineString()),
+		foldSQLiteSearchText(strings.Join(bm.Aliases, "\n")))
+	return err
+}
+
+func scanSQLiteBookmarks(rows *sql.Rows) ([]Bookmark, error) {
+	defer rows.Close()
+
+	var bookmarks []Bookmark
+	for rows.Next() {
+		var bm Bookmark
+		var uri, tags, whenAdded, lastUpdated, data string
+
+		err := rows.Scan(&bm.Number, &uri, &bm.Name, &bm.Description, &tags, &whenAdded, &lastUpdated, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rows written before the data column was added only have the columns
+		if len(data) > 0 {
+			bm = Bookmark{}
+			if err = json.Unmarshal([]byte(data), &bm); err != nil {
+				return nil, err
+			}
+
+			bookmarks = append(bookmarks, bm)
+			continue
+		}
+
+		if err = bm.Url.Parse(uri); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(tags), &bm.Tags); err != nil {
+			return nil, err
+		}
+		if bm.WhenAdded, err = time.Parse(time.RFC3339Nano, whenAdded); err != nil {
+			return nil, err
+		}
+		if bm.LastUpdated, err = time.Parse(time.RFC3339Nano, lastUpdated); err != nil {
+			return nil, err
+		}
+
+		bookmarks = append(bookmarks, bm)
+	}
+
+	return bookmarks, rows.Err()
+}
+
+func quoteFTSString(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// Folds text so the index matches at least whatever subStringMatches does,
+// dropping case, accents and formatting and decomposing compatibility forms
+// such as ligatures. Matches the index then finds may be false positives.
+func foldSQLiteSearchText(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,317 @@
+package db_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func createTestSQLiteStore(t *testing.T) (*db.SQLiteStore, func()) {
+	dir, _ := ioutil.TempDir("", "voile")
+
+	s, err := db.NewSQLiteStore(filepath.Join(dir, "bookmarks.db"))
+	assert.Nil(t, err)
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSQLiteStoreLoadEmpty(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, bmks.Len())
+}
+
+func TestSQLiteStoreSaveAndLoad(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	bmks.Bookmarks[0].Description = "Some\nlines"
+	bmks.Bookmarks[0].WhenAdded = time.Date(2018, time.November, 2, 10, 0, 0, 0, time.UTC)
+	assert.Nil(t, s.Save(&bmks))
+
+	loaded, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, loaded.Len())
+
+	bm, err := loaded.GetByNumber(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "one", bm.Name)
+	assert.Equal(t, "https://github.com", bm.Url.String())
+	assert.Equal(t, "Some\nlines", bm.Description)
+	assert.Equal(t, []string{"news", "weather"}, bm.Tags.Tags)
+	assert.True(t, bmks.Bookmarks[0].WhenAdded.Equal(bm.WhenAdded))
+}
+
+func TestSQLiteStoreSaveReplaces(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	assert.Nil(t, s.Save(&bmks))
+
+	bmks.DeleteByNumber(2)
+	assert.Nil(t, s.Save(&bmks))
+
+	loaded, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, loaded.Len())
+
+	results, err := s.Search(db.SearchTerms{Url: "facebook"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, results.Len())
+}
+
+func TestSQLiteStoreSaveOnlyChanged(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	require.NoError(t, s.Save(&bmks))
+
+	// Record every row written from another connection
+	conn, err := sql.Open("sqlite", s.Path())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec(`
+CREATE TABLE writes (number INTEGER);
+CREATE TRIGGER log_insert AFTER INSERT ON bookmarks BEGIN INSERT INTO writes VALUES (new.number); END;
+CREATE TRIGGER log_update AFTER UPDATE ON bookmarks BEGIN INSERT INTO writes VALUES (new.number); END;
+CREATE TRIGGER log_delete AFTER DELETE ON bookmarks BEGIN INSERT INTO writes VALUES (old.number); END;
+`)
+	require.NoError(t, err)
+
+	bmks, err = s.Load()
+	require.NoError(t, err)
+	bmks.Bookmarks[1].Name = "changed"
+	bmks.DeleteByNumber(3)
+	require.NoError(t, s.Save(&bmks))
+
+	var writes []int
+	rows, err := conn.Query("SELECT number FROM writes ORDER BY number")
+	require.NoError(t, err)
+	for rows.Next() {
+		var number int
+		require.NoError(t, rows.Scan(&number))
+		writes = append(writes, number)
+	}
+	rows.Close()
+	assert.Equal(t, []int{2, 2, 3}, writes)
+
+	loaded, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, 2, loaded.Len())
+	assert.Equal(t, "changed", loaded.Bookmarks[1].Name)
+
+	results, err := s.Search(db.SearchTerms{Name: "changed"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, results.Len())
+}
+
+func TestSQLiteStoreSaveSwappedUrls(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	require.NoError(t, s.Save(&bmks))
+
+	first, second := bmks.Bookmarks[0].Url, bmks.Bookmarks[1].Url
+	bmks.Bookmarks[0].Url, bmks.Bookmarks[1].Url = second, first
+	require.NoError(t, s.Save(&bmks))
+
+	loaded, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, second.String(), loaded.Bookmarks[0].Url.String())
+	assert.Equal(t, first.String(), loaded.Bookmarks[1].Url.String())
+}
+
+func TestSQLiteStoreSearch(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	assert.Nil(t, s.Save(&bmks))
+
+	results, err := s.Search(db.SearchTerms{Url: "BBC.co"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, results.Len())
+	assert.Equal(t, 3, results.Bookmarks[0].Number)
+
+	results, err = s.Search(db.SearchTerms{Tags: []string{"news"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, results.Len())
+
+	results, err = s.Search(db.SearchTerms{Tags: []string{"news", "software"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, results.Len())
+	assert.Equal(t, 3, results.Bookmarks[0].Number)
+}
+
+func TestSQLiteStoreSearchAliases(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	require.NoError(t, bmks.MoveUrl(1, "https://gitlab.com"))
+	require.NoError(t, s.Save(&bmks))
+
+	results, err := s.Search(db.SearchTerms{Url: "github"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, results.Len())
+	assert.Equal(t, 1, results.Bookmarks[0].Number)
+}
+
+func TestSQLiteStoreSearchFolding(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	bmks.Bookmarks[0].Name = "Café ﬁlms"
+	require.NoError(t, s.Save(&bmks))
+
+	// Whatever matches without the index is found with it
+	for _, term := range []string{"CAFÉ", "café", "films", "FILMS"} {
+		assert.True(t, bmks.Bookmarks[0].NameMatches(term), term)
+
+		results, err := s.Search(db.SearchTerms{Name: term})
+		require.NoError(t, err)
+		_, err = results.GetByNumber(1)
+		assert.Nil(t, err, term)
+	}
+}
+
+func TestSQLiteStoreIndexRebuilt(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	require.NoError(t, s.Save(&bmks))
+
+	// Index as created by version 2
+	conn, err := sql.Open("sqlite", s.Path())
+	require.NoError(t, err)
+	_, err = conn.Exec(`
+DROP TABLE bookmarks_fts;
+CREATE VIRTUAL TABLE bookmarks_fts USING fts5(title, uri, description, tags, tokenize = 'trigram');
+PRAGMA user_version = 2;
+`)
+	require.NoError(t, err)
+	conn.Close()
+
+	migrated, err := db.NewSQLiteStore(s.Path())
+	require.NoError(t, err)
+	defer migrated.Close()
+
+	results, err := migrated.Search(db.SearchTerms{Url: "BBC.co"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, results.Len())
+}
+
+func TestSQLiteStoreSearchShortTerm(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	assert.Nil(t, s.Save(&bmks))
+
+	// Terms too short for the index return every bookmark
+	results, err := s.Search(db.SearchTerms{Name: "tw"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, results.Len())
+}
+
+func TestSQLiteStoreSearchQuotes(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	bmks.Bookmarks[1].Description = `A "quoted" description`
+	assert.Nil(t, s.Save(&bmks))
+
+	results, err := s.Search(db.SearchTerms{Description: `"quoted"`})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, results.Len())
+	assert.Equal(t, 2, results.Bookmarks[0].Number)
+}
+
+func TestNewStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	s, err := db.NewStore("", filepath.Join(dir, "bookmarks.json"))
+	assert.Nil(t, err)
+	assert.IsType(t, &db.JSONFileStore{}, s)
+
+	s, err = db.NewStore("", filepath.Join(dir, "bookmarks.sqlite"))
+	assert.Nil(t, err)
+	assert.IsType(t, &db.SQLiteStore{}, s)
+
+	s, err = db.NewStore(db.SQLiteStorage, filepath.Join(dir, "bookmarks"))
+	assert.Nil(t, err)
+	assert.IsType(t, &db.SQLiteStore{}, s)
+
+	_, err = db.NewStore("nope", filepath.Join(dir, "bookmarks"))
+	assert.NotNil(t, err)
+}
+
+func TestSQLiteStoreChangedOnDisk(t *testing.T) {
+	first, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	second, err := db.NewSQLiteStore(first.Path())
+	assert.Nil(t, err)
+	defer second.Close()
+
+	bmks, _ := first.Load()
+	otherBmks, _ := second.Load()
+
+	bmks.NewEntry().Url.Parse("https://github.com")
+	assert.Nil(t, first.Save(&bmks))
+
+	otherBmks.NewEntry().Url.Parse("https://bbc.co.uk")
+	assert.Equal(t, db.ErrLibraryChanged, second.Save(&otherBmks))
+
+	bmks.NewEntry().Url.Parse("https://bbc.co.uk")
+	assert.Nil(t, first.Save(&bmks))
+}
+
+func TestSQLiteStoreSaveAndLoadLinkCheck(t *testing.T) {
+	s, cleanup := createTestSQLiteStore(t)
+	defer cleanup()
+
+	bmks := createTestLibrary()
+	bmks.Bookmarks[1].LinkCheck = &db.LinkCheck{
+		Checked:     time.Date(2020, time.March, 4, 12, 0, 0, 0, time.UTC),
+		StatusCode:  200,
+		RedirectUrl: "https://example.com/moved",
+	}
+	assert.Nil(t, s.Save(&bmks))
+
+	loaded, err := s.Load()
+	assert.Nil(t, err)
+
+	bm, err := loaded.GetByNumber(1)
+	assert.Nil(t, err)
+	assert.Nil(t, bm.LinkCheck)
+
+	bm, err = loaded.GetByNumber(2)
+	assert.Nil(t, err)
+	assert.NotNil(t, bm.LinkCheck)
+	assert.Equal(t, 200, bm.LinkCheck.StatusCode)
+	assert.Equal(t, "https://example.com/moved", bm.LinkCheck.RedirectUrl)
+	assert.True(t, bm.LinkCheck.IsRedirected())
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	JSONStorage   = "json"
+	SQLiteStorage = "sqlite"
+)
+
+type Store interface {
+	Load() (BookmarkLibrary, error)
+	Save(bmks *BookmarkLibrary) error
+	Lock() error
+	Unlock() error
+}
+
+// Implemented by stores that persist the library to a single file on disk
+type FileStore interface {
+	Store
+	Path() string
+}
+
+// Fields to search in, empty fields are not searched
+type SearchTerms struct {
+	Name        string
+	Url         string
+	Description string
+	Tags        []string
+}
+
+// Implemented by stores that can use an index to narrow down a library.
+// Results may include false positives but must never omit a match.
+type SearchableStore interface {
+	Store
+	Search(terms SearchTerms) (BookmarkLibrary, error)
+}
+
+func StorageForFilename(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return SQLiteStorage
+	default:
+		return JSONStorage
+	}
+}
+
+func NewStore(storage, filename string) (Store, error) {
+	if len(storage) == 0 {
+		storage = StorageForFilename(filename)
+	}
+
+	switch storage {
+	case JSONStorage:
+		return NewJSONFileStore(filename), nil
+	case SQLiteStorage:
+		return NewSQLiteStore(filename)
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown storage type %s", storage))
+	}
+}
+
+type MemoryStore struct {
+	Bookmarks []Bookmark
+	mutex     sync.Mutex
+}
+
+func (s *MemoryStore) Load() (BookmarkLibrary, error) {
+	var bmks BookmarkLibrary
+
+	// Copy so that modifications are only seen after a save
+	bmks.Bookmarks = copyBookmarks(s.Bookmarks)
+
+	err := bmks.Verify()
+	if err != nil {
+		return bmks, err
+	}
+
+	sort.Sort(&bmks)
+
+	return bmks, nil
+}
+
+func (s *MemoryStore) Save(bmks *BookmarkLibrary) error {
+	err := bmks.Verify()
+	if err != nil {
+		return err
+	}
+
+	s.Bookmarks = copyBookmarks(bmks.Bookmarks)
+
+	return nil
+}
+
+// Copies bookmarks along with the slices and structs they refer to
+func copyBookmarks(bookmarks []Bookmark) []Bookmark {
+	copied := append([]Bookmark{}, bookmarks...)
+	for i := range copied {
+		bm := &(copied[i])
+		if bm.Tags.Tags != nil {
+			bm.Tags.Tags = append([]string{}, bm.Tags.Tags...)
+		}
+		if bm.Aliases != nil {
+			bm.Aliases = append([]string{}, bm.Aliases...)
+		}
+		if bm.Snapshots != nil {
+			bm.Snapshots = append([]Snapshot{}, bm.Snapshots...)
+		}
+		if bm.LinkCheck != nil {
+			linkCheck := *bm.LinkCheck
+			bm.LinkCheck = &linkCheck
+		}
+		if bm.Metadata != nil {
+			metadata := *bm.Metadata
+			if metadata.Published != nil {
+				published := *metadata.Published
+				metadata.Published = &published
+			}
+			bm.Metadata = &metadata
+		}
+	}
+	return copied
+}
+
+func (s *MemoryStore) Lock() error {
+	s.mutex.Lock()
+	return nil
+}
+
+func (s *MemoryStore) Unlock() error {
+	s.mutex.Unlock()
+	return nil
+}
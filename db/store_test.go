@@ -0,0 +1,211 @@
+package db_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func TestMemoryStoreLoadEmpty(t *testing.T) {
+	var s db.MemoryStore
+
+	bmks, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, bmks.Len())
+}
+
+func TestMemoryStoreSaveAndLoad(t *testing.T) {
+	var s db.MemoryStore
+
+	bmks := createTestLibrary()
+	assert.Nil(t, s.Save(&bmks))
+
+	loaded, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, loaded.Len())
+
+	bm, err := loaded.GetByNumber(2)
+	assert.Nil(t, err)
+	assert.Equal(t, "two", bm.Name)
+}
+
+func TestMemoryStoreLoadIsCopy(t *testing.T) {
+	var s db.MemoryStore
+
+	bmks := createTestLibrary()
+	assert.Nil(t, s.Save(&bmks))
+
+	loaded, _ := s.Load()
+	loaded.DeleteByNumber(1)
+
+	reloaded, _ := s.Load()
+	assert.Equal(t, 3, reloaded.Len())
+}
+
+func TestMemoryStoreCopiesFields(t *testing.T) {
+	var s db.MemoryStore
+
+	bmks := createTestLibrary()
+	bmks.Bookmarks[0].Aliases = []string{"https://old.example.com"}
+	bmks.Bookmarks[0].Snapshots = []db.Snapshot{{File: "a.html"}}
+	assert.Nil(t, s.Save(&bmks))
+
+	// Changes to the saved library are not seen
+	bmks.Bookmarks[0].Tags.Tags[0] = "saved"
+
+	loaded, _ := s.Load()
+	assert.Equal(t, "news", loaded.Bookmarks[0].Tags.Tags[0])
+
+	// Nor are changes to a loaded library until it is saved
+	loaded.Bookmarks[0].Tags.Tags[0] = "loaded"
+	loaded.Bookmarks[0].Aliases[0] = "https://changed.example.com"
+	loaded.Bookmarks[0].Snapshots[0].File = "b.html"
+
+	reloaded, _ := s.Load()
+	assert.Equal(t, "news", reloaded.Bookmarks[0].Tags.Tags[0])
+	assert.Equal(t, []string{"https://old.example.com"}, reloaded.Bookmarks[0].Aliases)
+	assert.Equal(t, "a.html", reloaded.Bookmarks[0].Snapshots[0].File)
+}
+
+func TestMemoryStoreSaveInvalid(t *testing.T) {
+	var s db.MemoryStore
+
+	bmks := db.BookmarkLibrary{
+		Bookmarks: []db.Bookmark{
+			{Number: 7},
+			{Number: 7},
+		},
+	}
+
+	assert.NotNil(t, s.Save(&bmks))
+	assert.Equal(t, 0, len(s.Bookmarks))
+}
+
+func TestJSONFileStoreLoadMissingFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	s := db.NewJSONFileStore(filepath.Join(dir, "bookmarks.json"))
+
+	bmks, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, bmks.Len())
+}
+
+func TestJSONFileStoreSaveAndLoad(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	s := db.NewJSONFileStore(filepath.Join(dir, "bookmarks.json"))
+
+	bmks := createTestLibrary()
+	assert.Nil(t, s.Save(&bmks))
+
+	loaded, err := s.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, loaded.Len())
+
+	bm, err := loaded.GetByNumber(3)
+	assert.Nil(t, err)
+	assert.Equal(t, "three", bm.Name)
+	assert.Equal(t, "https://bbc.co.uk", bm.Url.String())
+	assert.Equal(t, []string{"news", "software"}, bm.Tags.Tags)
+}
+
+func TestJSONFileStoreLoadInvalidJSON(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "bookmarks.json")
+	ioutil.WriteFile(filename, []byte("[{"), 0644)
+
+	_, err := db.NewJSONFileStore(filename).Load()
+	assert.NotNil(t, err)
+}
+
+func TestJSONFileStoreChangedOnDisk(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "bookmarks.json")
+
+	first := db.NewJSONFileStore(filename)
+	second := db.NewJSONFileStore(filename)
+
+	bmks, _ := first.Load()
+	otherBmks, _ := second.Load()
+
+	bmks.NewEntry().Url.Parse("https://github.com")
+	assert.Nil(t, first.Save(&bmks))
+
+	otherBmks.NewEntry().Url.Parse("https://bbc.co.uk")
+	assert.Equal(t, db.ErrLibraryChanged, second.Save(&otherBmks))
+
+	// Saving again from the store that wrote the file is fine
+	bmks.NewEntry().Url.Parse("https://bbc.co.uk")
+	assert.Nil(t, first.Save(&bmks))
+
+	loaded, _ := second.Load()
+	assert.Equal(t, 2, loaded.Len())
+}
+
+func TestJSONFileStoreSaveLeavesNoTemporaryFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	s := db.NewJSONFileStore(filepath.Join(dir, "bookmarks.json"))
+
+	bmks := createTestLibrary()
+	assert.Nil(t, s.Save(&bmks))
+	assert.Nil(t, s.Save(&bmks))
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, "bookmarks.json", files[0].Name())
+}
+
+func TestJSONFileStoreLock(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	oldTimeout := db.LockTimeout
+	db.LockTimeout = 200 * time.Millisecond
+	defer func() { db.LockTimeout = oldTimeout }()
+
+	filename := filepath.Join(dir, "bookmarks.json")
+
+	first := db.NewJSONFileStore(filename)
+	second := db.NewJSONFileStore(filename)
+
+	assert.Nil(t, first.Lock())
+	assert.NotNil(t, second.Lock())
+
+	assert.Nil(t, first.Unlock())
+	assert.Nil(t, second.Lock())
+	assert.Nil(t, second.Unlock())
+}
+
+func TestJSONFileStoreLockInGitRepository(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, ".git"), 0755))
+
+	s := db.NewJSONFileStore(filepath.Join(dir, "bookmarks.json"))
+	assert.Nil(t, s.Lock())
+	defer s.Unlock()
+
+	// Nothing but the .git directory is created next to the library
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, ".git", files[0].Name())
+
+	_, err := os.Stat(filepath.Join(dir, ".git", "voile-bookmarks.json.lock"))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Limits how many aliases are followed, in case they form a loop
+const maxTagAliasDepth = 16
+
+// Maps alternative tag names to the tag used instead, e.g. golang to go
+type TagAliases map[string]string
+
+// Tag aliases kept next to a library file
+func TagAliasesForFile(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + ".tags.json"
+}
+
+// Loads tag aliases, a missing file has no aliases
+func LoadTagAliases(filename string) (TagAliases, error) {
+	aliases := make(TagAliases)
+
+	raw, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return aliases, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(raw, &aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+func (ta TagAliases) Save(filename string) error {
+	raw, err := json.MarshalIndent(ta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, raw, 0644)
+}
+
+// Returns the tag to use in place of the given tag, which also applies to
+// tags below an alias in the hierarchy
+func (ta TagAliases) Resolve(tag string) string {
+	for i := 0; i < maxTagAliasDepth; i++ {
+		resolved := false
+		for _, t := range TagAndAncestors(tag) {
+			if to, ok := ta[t]; ok {
+				tag, _ = RenameTag(tag, t, to)
+				resolved = true
+				break
+			}
+		}
+
+		if !resolved {
+			break
+		}
+	}
+
+	return tag
+}
+
+// Replaces any aliased tags, returning true if the list changed
+func (ta TagAliases) Apply(tl *TagList) bool {
+	changed := false
+
+	for _, t := range append([]string{}, tl.Tags...) {
+		if resolved := ta.Resolve(t); resolved != t {
+			tl.Remove(t)
+			tl.Append(resolved)
+			changed = true
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,70 @@
+package db_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func TestTagAliasesForFile(t *testing.T) {
+	assert.Equal(t, filepath.Join("library", "bookmarks.tags.json"),
+		db.TagAliasesForFile(filepath.Join("library", "bookmarks.json")))
+}
+
+func TestTagAliasesLoadMissing(t *testing.T) {
+	aliases, err := db.LoadTagAliases(filepath.Join("does", "not", "exist.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(aliases))
+}
+
+func TestTagAliasesSaveAndLoad(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "voile")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "bookmarks.tags.json")
+	assert.Nil(t, db.TagAliases{"golang": "go"}.Save(filename))
+
+	aliases, err := db.LoadTagAliases(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, db.TagAliases{"golang": "go"}, aliases)
+}
+
+func TestTagAliasesResolve(t *testing.T) {
+	aliases := db.TagAliases{
+		"golang": "lang/go",
+		"go":     "lang/go",
+		"py":     "python",
+		"python": "lang/python",
+	}
+
+	assert.Equal(t, "lang/go", aliases.Resolve("golang"))
+	assert.Equal(t, "lang/go/testing", aliases.Resolve("golang/testing"))
+	assert.Equal(t, "lang/python", aliases.Resolve("py"))
+	assert.Equal(t, "news", aliases.Resolve("news"))
+	assert.Equal(t, "gopher", aliases.Resolve("gopher"))
+}
+
+func TestTagAliasesResolveLoop(t *testing.T) {
+	aliases := db.TagAliases{
+		"a": "b",
+		"b": "a",
+	}
+
+	// Gives up rather than looping forever
+	aliases.Resolve("a")
+}
+
+func TestTagAliasesApply(t *testing.T) {
+	aliases := db.TagAliases{"golang": "go"}
+
+	tl := db.TagList{Tags: []string{"golang", "news"}}
+	assert.True(t, aliases.Apply(&tl))
+	assert.Equal(t, []string{"go", "news"}, tl.Tags)
+
+	assert.False(t, aliases.Apply(&tl))
+}
@@ -8,6 +8,9 @@ import (
 	"strings"
 )
 
+// Separates the levels of a hierarchical tag, e.g. lang/go
+const TagSeparator = "/"
+
 type TagList struct {
 	Tags []string
 }
@@ -44,6 +47,11 @@ func (tl TagList) MultilineString() string {
 	return strings.Join(tl.Tags, "\n")
 }
 
+func (tl *TagList) Contains(tag string) bool {
+	_, err := tl.search(tag)
+	return err == nil
+}
+
 func (tl *TagList) ContainsAllTags(tags []string) bool {
 	if len(tags) == 0 {
 		return false
@@ -58,6 +66,30 @@ func (tl *TagList) ContainsAllTags(tags []string) bool {
 	return true
 }
 
+// Checks for the tag or any tag below it in the hierarchy
+func (tl *TagList) HasTag(tag string) bool {
+	for _, t := range tl.Tags {
+		if IsTagOrDescendant(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (tl *TagList) HasAllTags(tags []string) bool {
+	if len(tags) == 0 {
+		return false
+	}
+
+	for _, t := range tags {
+		if !tl.HasTag(t) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (tl *TagList) Append(tag string) {
 	tag = strings.TrimSpace(tag)
 	if len(tag) > 0 {
@@ -94,9 +126,75 @@ func (tl *TagList) search(tag string) (int, error) {
 	return 0, errors.New(fmt.Sprintf("No tag found matching %s", tag))
 }
 
+func IsTagOrDescendant(tag, ancestor string) bool {
+	return tag == ancestor || strings.HasPrefix(tag, ancestor+TagSeparator)
+}
+
+// Returns the tag and every level above it, e.g. lang/go gives lang/go and lang
+func TagAndAncestors(tag string) []string {
+	tags := []string{tag}
+	for i := strings.LastIndex(tag, TagSeparator); i > 0; i = strings.LastIndex(tag, TagSeparator) {
+		tag = tag[:i]
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Replaces the tag, or the part of a descendant tag matching it
+func RenameTag(tag, from, to string) (string, bool) {
+	if !IsTagOrDescendant(tag, from) {
+		return tag, false
+	}
+	return to + tag[len(from):], true
+}
+
 type TagCount map[string]int
 
 type AllTags struct {
 	Tags  TagList
 	Count TagCount
+
+	// Bookmarks with each tag or any tag below it
+	Total TagCount
+}
+
+type TagTreeNode struct {
+	Tag      string
+	Children []TagTreeNode
+}
+
+// Last level of the tag
+func (n *TagTreeNode) Name() string {
+	return n.Tag[strings.LastIndex(n.Tag, TagSeparator)+1:]
+}
+
+// Arranges the tags by hierarchy, including levels that are not used as a tag
+// themselves
+func (at *AllTags) Tree() []TagTreeNode {
+	var all TagList
+	for _, t := range at.Tags.Tags {
+		for _, a := range TagAndAncestors(t) {
+			all.Append(a)
+		}
+	}
+
+	children := make(map[string][]string)
+	for _, t := range all.Tags {
+		parent := ""
+		if ancestors := TagAndAncestors(t); len(ancestors) > 1 {
+			parent = ancestors[1]
+		}
+		children[parent] = append(children[parent], t)
+	}
+
+	var build func(parent string) []TagTreeNode
+	build = func(parent string) []TagTreeNode {
+		var nodes []TagTreeNode
+		for _, t := range children[parent] {
+			nodes = append(nodes, TagTreeNode{t, build(t)})
+		}
+		return nodes
+	}
+
+	return build("")
 }
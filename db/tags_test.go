@@ -132,3 +132,65 @@ func TestTagListContainsAllTagsEmptyString(t *testing.T) {
 
 	assert.False(t, tl.ContainsAllTags([]string{""}))
 }
+
+func TestTagListHasTag(t *testing.T) {
+	tl := db.TagList{
+		Tags: []string{"lang/go", "news"},
+	}
+
+	assert.True(t, tl.HasTag("lang"))
+	assert.True(t, tl.HasTag("lang/go"))
+	assert.True(t, tl.HasTag("news"))
+	assert.False(t, tl.HasTag("lang/rust"))
+	assert.False(t, tl.HasTag("lan"))
+	assert.False(t, tl.HasTag("lang/go/x"))
+}
+
+func TestTagListHasAllTags(t *testing.T) {
+	tl := db.TagList{
+		Tags: []string{"lang/go", "news"},
+	}
+
+	assert.True(t, tl.HasAllTags([]string{"lang", "news"}))
+	assert.False(t, tl.HasAllTags([]string{"lang", "weather"}))
+	assert.False(t, tl.HasAllTags([]string{}))
+}
+
+func TestTagAndAncestors(t *testing.T) {
+	assert.Equal(t, []string{"a"}, db.TagAndAncestors("a"))
+	assert.Equal(t, []string{"a/b/c", "a/b", "a"}, db.TagAndAncestors("a/b/c"))
+}
+
+func TestRenameTag(t *testing.T) {
+	tag, ok := db.RenameTag("lang/go", "lang", "code")
+	assert.True(t, ok)
+	assert.Equal(t, "code/go", tag)
+
+	tag, ok = db.RenameTag("lang", "lang", "code")
+	assert.True(t, ok)
+	assert.Equal(t, "code", tag)
+
+	tag, ok = db.RenameTag("language", "lang", "code")
+	assert.False(t, ok)
+	assert.Equal(t, "language", tag)
+}
+
+func TestAllTagsTree(t *testing.T) {
+	tags := db.AllTags{
+		Tags: db.TagList{Tags: []string{"lang-x", "lang/go", "lang/rust", "news"}},
+	}
+
+	tree := tags.Tree()
+	assert.Equal(t, 3, len(tree))
+
+	assert.Equal(t, "lang", tree[0].Tag)
+	assert.Equal(t, 2, len(tree[0].Children))
+	assert.Equal(t, "lang/go", tree[0].Children[0].Tag)
+	assert.Equal(t, "go", tree[0].Children[0].Name())
+	assert.Equal(t, "lang/rust", tree[0].Children[1].Tag)
+
+	assert.Equal(t, "lang-x", tree[1].Tag)
+	assert.Equal(t, 0, len(tree[1].Children))
+
+	assert.Equal(t, "news", tree[2].Tag)
+}
@@ -2,9 +2,27 @@ package db
 
 import (
 	"encoding/json"
+	"net"
 	"net/url"
+	"sort"
+	"strings"
 )
 
+// Query parameters used only to track where a visitor came from
+var trackingQueryParams = []string{
+	"fbclid",
+	"gclid",
+	"igshid",
+	"mc_cid",
+	"mc_eid",
+	"ref_src",
+	"yclid",
+	"_hsenc",
+	"_hsmi",
+}
+
+const trackingQueryParamPrefix = "utm_"
+
 type Url struct {
 	Url url.URL
 }
@@ -32,3 +50,73 @@ func (u *Url) UnmarshalJSON(b []byte) error {
 	json.Unmarshal(b, &urlStr)
 	return u.Parse(urlStr)
 }
+
+// Returns a form of the URL that is the same for addresses that almost
+// certainly refer to the same page, ignoring the scheme, a leading www, a
+// trailing slash, the fragment and tracking query parameters
+func (u *Url) Normalised() string {
+	nu := u.Url
+
+	// URLs entered without a scheme parse as a path
+	if len(nu.Host) == 0 && len(nu.Scheme) == 0 && len(nu.Path) > 0 {
+		if withScheme, err := url.Parse("http://" + nu.String()); err == nil {
+			nu = *withScheme
+		}
+	}
+
+	// Nothing can be safely ignored in opaque URLs (e.g. mailto:) or those
+	// without a host
+	if len(nu.Opaque) > 0 || len(nu.Host) == 0 {
+		return u.Url.String()
+	}
+
+	host := strings.ToLower(nu.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	if port := nu.Port(); len(port) > 0 && port != "80" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	path := strings.TrimRight(nu.EscapedPath(), "/")
+
+	// Keep remaining query parameters in a consistent order
+	query := nu.Query()
+	for key := range query {
+		if isTrackingQueryParam(key) {
+			query.Del(key)
+		}
+	}
+
+	normalised := host + path
+	if len(query) > 0 {
+		keys := make([]string, 0, len(query))
+		for key := range query {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var params []string
+		for _, key := range keys {
+			for _, value := range query[key] {
+				params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(value))
+			}
+		}
+		normalised += "?" + strings.Join(params, "&")
+	}
+
+	return normalised
+}
+
+func isTrackingQueryParam(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasPrefix(key, trackingQueryParamPrefix) {
+		return true
+	}
+
+	for _, p := range trackingQueryParams {
+		if key == p {
+			return true
+		}
+	}
+
+	return false
+}
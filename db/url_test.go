@@ -0,0 +1,46 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+)
+
+func normaliseTestUrl(s string) string {
+	var u db.Url
+	u.Parse(s)
+	return u.Normalised()
+}
+
+func TestUrlNormalised(t *testing.T) {
+	expected := "x.com/a"
+
+	for _, s := range []string{
+		"http://x.com/a",
+		"https://x.com/a",
+		"https://www.x.com/a/",
+		"https://WWW.X.COM/a",
+		"https://x.com:443/a",
+		"x.com/a",
+		"x.com/a?utm_source=feed&utm_medium=rss",
+		"https://x.com/a?fbclid=123#comments",
+	} {
+		assert.Equal(t, expected, normaliseTestUrl(s), s)
+	}
+}
+
+func TestUrlNormalisedKeepsDifferences(t *testing.T) {
+	assert.Equal(t, "x.com/A", normaliseTestUrl("https://x.com/A"))
+	assert.Equal(t, "x.com:8080/a", normaliseTestUrl("https://x.com:8080/a"))
+	assert.Equal(t, "x.com/a?id=2", normaliseTestUrl("https://x.com/a?id=2&utm_campaign=c"))
+	assert.Equal(t, "x.com/a?a=1&b=2", normaliseTestUrl("https://x.com/a?b=2&a=1"))
+	assert.Equal(t, "x.com", normaliseTestUrl("https://x.com/"))
+
+	// Opaque URLs and those without a host are kept whole
+	assert.Equal(t, "mailto:someone@example.com", normaliseTestUrl("mailto:someone@example.com"))
+	assert.Equal(t, "urn:isbn:0451450523", normaliseTestUrl("urn:isbn:0451450523"))
+	assert.Equal(t, "javascript:void(0)", normaliseTestUrl("javascript:void(0)"))
+	assert.Equal(t, "file:///home/me/notes.txt", normaliseTestUrl("file:///home/me/notes.txt"))
+}
@@ -0,0 +1,36 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanNixon/voile/db"
+)
+
+var csvHeader = []string{"number", "url", "title", "description", "tags", "added", "updated"}
+
+// Exports one bookmark per row, tags are comma separated within their column
+type CSVExporter struct{}
+
+func (CSVExporter) Export(w io.Writer, bookmarks []db.Bookmark) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+
+	for _, bm := range bookmarks {
+		cw.Write([]string{
+			strconv.Itoa(bm.Number),
+			bm.Url.String(),
+			bm.Name,
+			bm.Description,
+			strings.Join(bm.Tags.Tags, ","),
+			bm.WhenAdded.Format(time.RFC3339),
+			bm.LastUpdated.Format(time.RFC3339),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
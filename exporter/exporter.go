@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/DanNixon/voile/db"
+)
+
+type Exporter interface {
+	Export(w io.Writer, bookmarks []db.Bookmark) error
+}
+
+var exporters = map[string]Exporter{
+	"netscape": NetscapeExporter{},
+	"markdown": MarkdownExporter{},
+	"csv":      CSVExporter{},
+	"opml":     OPMLExporter{},
+	"org":      OrgExporter{},
+}
+
+func Formats() []string {
+	var formats []string
+	for f := range exporters {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func Get(format string) (Exporter, error) {
+	e, ok := exporters[format]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Unknown export format %s", format))
+	}
+	return e, nil
+}
@@ -0,0 +1,134 @@
+package exporter_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/exporter"
+	"github.com/DanNixon/voile/importer"
+)
+
+func createTestBookmarks() []db.Bookmark {
+	return []db.Bookmark{
+		{
+			Number: 1,
+			Name:   "BBC [News] & Weather",
+			Url: db.Url{Url: url.URL{
+				Scheme: "https",
+				Host:   "bbc.co.uk",
+			}},
+			Description: "British news\nand weather",
+			Tags: db.TagList{
+				Tags: []string{"news", "uk weather"},
+			},
+			WhenAdded:   time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+			LastUpdated: time.Date(2019, time.January, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Number: 2,
+			Url: db.Url{Url: url.URL{
+				Scheme: "https",
+				Host:   "github.com",
+			}},
+			WhenAdded:   time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC),
+			LastUpdated: time.Date(2019, time.February, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func export(t *testing.T, format string) string {
+	e, err := exporter.Get(format)
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	assert.Nil(t, e.Export(&buf, createTestBookmarks()))
+	return buf.String()
+}
+
+func TestGetExporter(t *testing.T) {
+	_, err := exporter.Get("nope")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []string{"csv", "markdown", "netscape", "opml", "org"}, exporter.Formats())
+}
+
+func TestNetscapeExportRoundTrip(t *testing.T) {
+	out := export(t, "netscape")
+
+	bms, err := importer.NetscapeImporter{}.Import(bytes.NewBufferString(out))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bms))
+
+	expected := createTestBookmarks()
+	for i := range expected {
+		assert.Equal(t, expected[i].Name, bms[i].Name)
+		assert.Equal(t, expected[i].Url.String(), bms[i].Url.String())
+		assert.Equal(t, expected[i].Description, bms[i].Description)
+		assert.Equal(t, expected[i].Tags.Tags, bms[i].Tags.Tags)
+		assert.True(t, expected[i].WhenAdded.Equal(bms[i].WhenAdded))
+		assert.True(t, expected[i].LastUpdated.Equal(bms[i].LastUpdated))
+	}
+}
+
+func TestNetscapeExportZeroTime(t *testing.T) {
+	bm := db.Bookmark{Number: 1}
+	bm.Url.Parse("https://example.com")
+
+	var buf bytes.Buffer
+	assert.Nil(t, exporter.NetscapeExporter{}.Export(&buf, []db.Bookmark{bm}))
+	assert.Contains(t, buf.String(), `<A HREF="https://example.com">`)
+	assert.NotContains(t, buf.String(), "ADD_DATE")
+	assert.NotContains(t, buf.String(), "LAST_MODIFIED")
+}
+
+func TestMarkdownExport(t *testing.T) {
+	assert.Equal(t, "- [BBC \\[News\\] & Weather](<https://bbc.co.uk>) `news` `uk weather`\n"+
+		"  British news\n"+
+		"  and weather\n"+
+		"- [https://github.com](<https://github.com>)\n",
+		export(t, "markdown"))
+}
+
+func TestCSVExport(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewBufferString(export(t, "csv"))).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, []string{"number", "url", "title", "description", "tags", "added", "updated"}, records[0])
+	assert.Equal(t, []string{
+		"1", "https://bbc.co.uk", "BBC [News] & Weather", "British news\nand weather",
+		"news,uk weather", "2019-01-01T00:00:00Z", "2019-01-02T00:00:00Z",
+	}, records[1])
+}
+
+func TestOPMLExport(t *testing.T) {
+	var doc struct {
+		Outlines []struct {
+			Text     string `xml:"text,attr"`
+			Url      string `xml:"url,attr"`
+			Category string `xml:"category,attr"`
+		} `xml:"body>outline"`
+	}
+	assert.Nil(t, xml.Unmarshal([]byte(export(t, "opml")), &doc))
+
+	assert.Equal(t, 2, len(doc.Outlines))
+	assert.Equal(t, "BBC [News] & Weather", doc.Outlines[0].Text)
+	assert.Equal(t, "https://bbc.co.uk", doc.Outlines[0].Url)
+	assert.Equal(t, "news,uk weather", doc.Outlines[0].Category)
+}
+
+func TestOrgExport(t *testing.T) {
+	assert.Equal(t, "* [[https://bbc.co.uk][BBC (News) & Weather]] :news:uk_weather:\n"+
+		"  [2019-01-01 Tue 00:00]\n"+
+		"  British news\n"+
+		"  and weather\n"+
+		"* [[https://github.com][https://github.com]]\n"+
+		"  [2019-02-01 Fri 00:00]\n",
+		export(t, "org"))
+}
@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DanNixon/voile/db"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, `[`, `\[`, `]`, `\]`, `*`, `\*`, `_`, `\_`, "`", "\\`")
+
+// Exports a Markdown list suitable for publishing as a reading list
+type MarkdownExporter struct{}
+
+func (MarkdownExporter) Export(w io.Writer, bookmarks []db.Bookmark) error {
+	bw := bufio.NewWriter(w)
+
+	for _, bm := range bookmarks {
+		name := bm.Name
+		if !bm.HasName() {
+			name = bm.Url.String()
+		}
+
+		// Angle brackets allow any characters in the URL
+		fmt.Fprintf(bw, "- [%s](<%s>)", markdownEscaper.Replace(name), bm.Url.String())
+
+		for _, t := range bm.Tags.Tags {
+			fmt.Fprintf(bw, " `%s`", strings.Replace(t, "`", "'", -1))
+		}
+		bw.WriteString("\n")
+
+		if len(bm.Description) > 0 {
+			for _, l := range strings.Split(bm.Description, "\n") {
+				fmt.Fprintf(bw, "  %s\n", l)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
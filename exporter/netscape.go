@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/DanNixon/voile/db"
+)
+
+const netscapeHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`
+
+// Exports the bookmarks.html format that every browser can import.
+// Tags are written to the TAGS attribute as understood by Firefox.
+type NetscapeExporter struct{}
+
+func (NetscapeExporter) Export(w io.Writer, bookmarks []db.Bookmark) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(netscapeHeader)
+
+	for _, bm := range bookmarks {
+		fmt.Fprintf(bw, `    <DT><A HREF="%s"`, html.EscapeString(bm.Url.String()))
+		// Unknown times are left out rather than written as year 1
+		if !bm.WhenAdded.IsZero() {
+			fmt.Fprintf(bw, ` ADD_DATE="%d"`, bm.WhenAdded.Unix())
+		}
+		if !bm.LastUpdated.IsZero() {
+			fmt.Fprintf(bw, ` LAST_MODIFIED="%d"`, bm.LastUpdated.Unix())
+		}
+		if bm.Tags.Len() > 0 {
+			fmt.Fprintf(bw, ` TAGS="%s"`, html.EscapeString(strings.Join(bm.Tags.Tags, ",")))
+		}
+		fmt.Fprintf(bw, ">%s</A>\n", html.EscapeString(bm.Name))
+
+		if len(bm.Description) > 0 {
+			fmt.Fprintf(bw, "    <DD>%s\n", html.EscapeString(bm.Description))
+		}
+	}
+
+	bw.WriteString("</DL><p>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,65 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/DanNixon/voile/db"
+)
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Created string        `xml:"head>dateCreated"`
+	Outline []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text        string `xml:"text,attr"`
+	Type        string `xml:"type,attr"`
+	Url         string `xml:"url,attr"`
+	Created     string `xml:"created,attr,omitempty"`
+	Category    string `xml:"category,attr,omitempty"`
+	Description string `xml:"description,attr,omitempty"`
+}
+
+// Exports an OPML 2.0 document of link outlines
+type OPMLExporter struct{}
+
+func (OPMLExporter) Export(w io.Writer, bookmarks []db.Bookmark) error {
+	doc := opmlDocument{
+		Version: "2.0",
+		Title:   "Bookmarks",
+		Created: time.Now().Format(time.RFC1123Z),
+	}
+
+	for _, bm := range bookmarks {
+		o := opmlOutline{
+			Text:        bm.Name,
+			Type:        "link",
+			Url:         bm.Url.String(),
+			Category:    strings.Join(bm.Tags.Tags, ","),
+			Description: bm.Description,
+		}
+		if !bm.WhenAdded.IsZero() {
+			o.Created = bm.WhenAdded.Format(time.RFC1123Z)
+		}
+		doc.Outline = append(doc.Outline, o)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
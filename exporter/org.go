@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/DanNixon/voile/db"
+)
+
+const orgTimestampFormat = "2006-01-02 Mon 15:04"
+
+// Exports an Org mode outline with one heading per bookmark
+type OrgExporter struct{}
+
+func (OrgExporter) Export(w io.Writer, bookmarks []db.Bookmark) error {
+	bw := bufio.NewWriter(w)
+
+	for _, bm := range bookmarks {
+		name := bm.Name
+		if !bm.HasName() {
+			name = bm.Url.String()
+		}
+
+		// Square brackets would terminate the link early
+		name = strings.NewReplacer("[", "(", "]", ")").Replace(name)
+		fmt.Fprintf(bw, "* [[%s][%s]]", bm.Url.String(), name)
+
+		if bm.Tags.Len() > 0 {
+			var tags []string
+			for _, t := range bm.Tags.Tags {
+				tags = append(tags, orgTag(t))
+			}
+			fmt.Fprintf(bw, " :%s:", strings.Join(tags, ":"))
+		}
+		bw.WriteString("\n")
+
+		if !bm.WhenAdded.IsZero() {
+			fmt.Fprintf(bw, "  [%s]\n", bm.WhenAdded.Format(orgTimestampFormat))
+		}
+
+		if len(bm.Description) > 0 {
+			// Indented so that lines starting with an asterisk are not headings
+			for _, l := range strings.Split(bm.Description, "\n") {
+				fmt.Fprintf(bw, "  %s\n", l)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Org tags may only contain letters, numbers, _, @, # and %
+func orgTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_@#%", r) {
+			return r
+		}
+		return '_'
+	}, tag)
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DanNixon/voile/db"
+)
+
+type Importer interface {
+	Import(r io.Reader) ([]db.Bookmark, error)
+}
+
+var importers = map[string]Importer{
+	"netscape": NetscapeImporter{},
+	"pinboard": PinboardImporter{},
+	"pocket":   PocketImporter{},
+	"raindrop": RaindropImporter{},
+
+	// Shaarli exports the Netscape format with tags in the TAGS attribute
+	"shaarli": NetscapeImporter{},
+}
+
+func Formats() []string {
+	var formats []string
+	for f := range importers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func Get(format string) (Importer, error) {
+	i, ok := importers[format]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Unknown import format %s", format))
+	}
+	return i, nil
+}
+
+// Reads CSV with a header row into maps keyed by lower case column name
+func readCSVRecords(r io.Reader) ([]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for i, h := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+	}
+
+	var records []map[string]string
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		rec := make(map[string]string)
+		for i, v := range row {
+			if i < len(header) {
+				rec[header[i]] = v
+			}
+		}
+		records = append(records, rec)
+	}
+}
+
+func parseUnixTimestamp(s string) time.Time {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/DanNixon/voile/db"
+)
+
+// Imports the bookmarks.html format exported by every browser.
+// Folders containing a bookmark become its tags.
+type NetscapeImporter struct{}
+
+func (NetscapeImporter) Import(r io.Reader) ([]db.Bookmark, error) {
+	var bookmarks []db.Bookmark
+
+	// Names of the enclosing folders, empty for lists without a heading
+	var folders []string
+	var folderName *strings.Builder
+
+	var link *db.Bookmark
+	var linkName strings.Builder
+
+	// Description of the last bookmark, if in a DD element
+	var desc *db.Bookmark
+
+	// Whether the last link was imported, a DD after a skipped link belongs
+	// to that link and not the bookmark before it
+	lastImported := false
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return bookmarks, nil
+			}
+			return bookmarks, z.Err()
+
+		case html.StartTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "h3":
+				folderName = &strings.Builder{}
+			case "dl":
+				name := ""
+				if folderName != nil {
+					name = strings.TrimSpace(folderName.String())
+					folderName = nil
+				}
+				folders = append(folders, name)
+				desc = nil
+				lastImported = false
+			case "dt":
+				desc = nil
+				lastImported = false
+			case "a":
+				link = newNetscapeBookmark(t.Attr, folders)
+				linkName.Reset()
+				lastImported = false
+			case "dd":
+				if lastImported {
+					desc = &bookmarks[len(bookmarks)-1]
+				}
+			}
+
+		case html.EndTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "a":
+				if link != nil {
+					link.Name = strings.TrimSpace(linkName.String())
+
+					// Relative links are e.g. Shaarli notes, which have no page
+					if link.Url.Url.IsAbs() {
+						bookmarks = append(bookmarks, *link)
+						lastImported = true
+					}
+					link = nil
+				}
+			case "dl":
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+				desc = nil
+				lastImported = false
+			}
+
+		case html.TextToken:
+			text := string(z.Text())
+			if folderName != nil {
+				folderName.WriteString(text)
+			} else if link != nil {
+				linkName.WriteString(text)
+			} else if desc != nil {
+				desc.Description = strings.TrimSpace(desc.Description + text)
+			}
+		}
+	}
+}
+
+func newNetscapeBookmark(attrs []html.Attribute, folders []string) *db.Bookmark {
+	bm := &db.Bookmark{}
+
+	for _, f := range folders {
+		bm.Tags.Append(f)
+	}
+
+	for _, a := range attrs {
+		switch a.Key {
+		case "href":
+			// Browser internal queries (e.g. Firefox smart bookmarks) are not
+			// useful outside of the browser
+			if strings.HasPrefix(a.Val, "place:") {
+				return bm
+			}
+			bm.Url.Parse(a.Val)
+		case "add_date":
+			bm.WhenAdded = parseUnixTimestamp(a.Val)
+		case "last_modified":
+			bm.LastUpdated = parseUnixTimestamp(a.Val)
+		case "tags":
+			bm.Tags.AppendFromString(a.Val)
+		}
+	}
+
+	if bm.LastUpdated.IsZero() {
+		bm.LastUpdated = bm.WhenAdded
+	}
+
+	return bm
+}
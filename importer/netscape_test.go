@@ -0,0 +1,67 @@
+package importer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/importer"
+)
+
+const testNetscapeFile = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file. -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><A HREF="https://github.com/" ADD_DATE="1546300800" LAST_MODIFIED="1546387200">GitHub</A>
+    <DT><H3 ADD_DATE="1546300800">News</H3>
+    <DL><p>
+        <DT><A HREF="https://bbc.co.uk/news" ADD_DATE="1546300800" TAGS="uk,daily">BBC &amp; News</A>
+        <DD>British news
+        <DT><H3>Weather</H3>
+        <DL><p>
+            <DT><A HREF="https://metoffice.gov.uk/">Met Office</A>
+        </DL><p>
+    </DL><p>
+    <DT><A HREF="place:sort=8&maxResults=10">Recent</A>
+    <DD>Recently visited
+    <DT><A HREF="https://golang.org/">Go</A>
+</DL><p>
+`
+
+func TestNetscapeImport(t *testing.T) {
+	bms, err := importer.NetscapeImporter{}.Import(strings.NewReader(testNetscapeFile))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(bms))
+
+	assert.Equal(t, "GitHub", bms[0].Name)
+	assert.Equal(t, "https://github.com/", bms[0].Url.String())
+	assert.Equal(t, 0, bms[0].Tags.Len())
+	assert.Equal(t, time.Unix(1546300800, 0), bms[0].WhenAdded)
+	assert.Equal(t, time.Unix(1546387200, 0), bms[0].LastUpdated)
+
+	assert.Equal(t, "BBC & News", bms[1].Name)
+	assert.Equal(t, "British news", bms[1].Description)
+	assert.Equal(t, []string{"News", "daily", "uk"}, bms[1].Tags.Tags)
+	assert.Equal(t, bms[1].WhenAdded, bms[1].LastUpdated)
+
+	assert.Equal(t, "Met Office", bms[2].Name)
+	assert.Equal(t, []string{"News", "Weather"}, bms[2].Tags.Tags)
+	assert.Equal(t, "", bms[2].Description)
+	assert.True(t, bms[2].WhenAdded.IsZero())
+
+	assert.Equal(t, "Go", bms[3].Name)
+	assert.Equal(t, 0, bms[3].Tags.Len())
+}
+
+func TestGetImporter(t *testing.T) {
+	i, err := importer.Get("netscape")
+	assert.Nil(t, err)
+	assert.IsType(t, importer.NetscapeImporter{}, i)
+
+	_, err = importer.Get("nope")
+	assert.NotNil(t, err)
+}
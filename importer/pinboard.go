@@ -0,0 +1,59 @@
+package importer
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/DanNixon/voile/db"
+)
+
+const ToReadTag = "toread"
+
+type pinboardPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"`
+	Extended    string `json:"extended"`
+	Time        string `json:"time"`
+	ToRead      string `json:"toread"`
+	Tags        string `json:"tags"`
+}
+
+// Imports the JSON export from Pinboard
+type PinboardImporter struct{}
+
+func (PinboardImporter) Import(r io.Reader) ([]db.Bookmark, error) {
+	var posts []pinboardPost
+	if err := json.NewDecoder(r).Decode(&posts); err != nil {
+		return nil, err
+	}
+
+	var bookmarks []db.Bookmark
+	for _, p := range posts {
+		var bm db.Bookmark
+		if err := bm.Url.Parse(p.Href); err != nil || !bm.Url.Url.IsAbs() {
+			continue
+		}
+
+		// Pinboard calls the title the description and the notes extended
+		bm.Name = strings.TrimSpace(p.Description)
+		bm.Description = strings.TrimSpace(p.Extended)
+
+		for _, t := range strings.Fields(p.Tags) {
+			bm.Tags.Append(t)
+		}
+		if p.ToRead == "yes" {
+			bm.Tags.Append(ToReadTag)
+		}
+
+		if t, err := time.Parse(time.RFC3339, p.Time); err == nil {
+			bm.WhenAdded = t
+			bm.LastUpdated = t
+		}
+
+		bookmarks = append(bookmarks, bm)
+	}
+
+	return bookmarks, nil
+}
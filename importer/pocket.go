@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/DanNixon/voile/db"
+)
+
+// Imports Pocket exports, either the older ril_export.html or the newer CSV
+// format. Unread items are tagged toread.
+type PocketImporter struct{}
+
+func (PocketImporter) Import(r io.Reader) ([]db.Bookmark, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("<")) {
+		return importPocketHTML(bytes.NewReader(raw))
+	}
+	return importPocketCSV(bytes.NewReader(raw))
+}
+
+func importPocketHTML(r io.Reader) ([]db.Bookmark, error) {
+	var bookmarks []db.Bookmark
+
+	// Items are listed under "Unread" and "Read Archive" headings
+	var heading *strings.Builder
+	unread := false
+
+	var link *db.Bookmark
+	var linkName strings.Builder
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return bookmarks, nil
+			}
+			return bookmarks, z.Err()
+
+		case html.StartTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "h1":
+				heading = &strings.Builder{}
+			case "a":
+				link = &db.Bookmark{}
+				linkName.Reset()
+				for _, a := range t.Attr {
+					switch a.Key {
+					case "href":
+						link.Url.Parse(a.Val)
+					case "time_added":
+						link.WhenAdded = parseUnixTimestamp(a.Val)
+						link.LastUpdated = link.WhenAdded
+					case "tags":
+						link.Tags.AppendFromString(a.Val)
+					}
+				}
+				if unread {
+					link.Tags.Append(ToReadTag)
+				}
+			}
+
+		case html.EndTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "h1":
+				if heading != nil {
+					unread = strings.EqualFold(strings.TrimSpace(heading.String()), "unread")
+					heading = nil
+				}
+			case "a":
+				if link != nil && link.Url.Url.IsAbs() {
+					link.Name = strings.TrimSpace(linkName.String())
+					bookmarks = append(bookmarks, *link)
+				}
+				link = nil
+			}
+
+		case html.TextToken:
+			if heading != nil {
+				heading.Write(z.Text())
+			} else if link != nil {
+				linkName.Write(z.Text())
+			}
+		}
+	}
+}
+
+func importPocketCSV(r io.Reader) ([]db.Bookmark, error) {
+	records, err := readCSVRecords(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	var bookmarks []db.Bookmark
+	for _, rec := range records {
+		var bm db.Bookmark
+		if err := bm.Url.Parse(rec["url"]); err != nil || !bm.Url.Url.IsAbs() {
+			continue
+		}
+
+		bm.Name = strings.TrimSpace(rec["title"])
+		bm.WhenAdded = parseUnixTimestamp(rec["time_added"])
+		bm.LastUpdated = bm.WhenAdded
+
+		// Tags are pipe separated
+		for _, t := range strings.Split(rec["tags"], "|") {
+			bm.Tags.Append(t)
+		}
+		if rec["status"] == "unread" {
+			bm.Tags.Append(ToReadTag)
+		}
+
+		bookmarks = append(bookmarks, bm)
+	}
+
+	return bookmarks, nil
+}
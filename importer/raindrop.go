@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/DanNixon/voile/db"
+)
+
+// Imports the CSV export from Raindrop.io, the collection (folder) an item
+// is in becomes a tag
+type RaindropImporter struct{}
+
+func (RaindropImporter) Import(r io.Reader) ([]db.Bookmark, error) {
+	records, err := readCSVRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var bookmarks []db.Bookmark
+	for _, rec := range records {
+		var bm db.Bookmark
+		if err := bm.Url.Parse(rec["url"]); err != nil || !bm.Url.Url.IsAbs() {
+			continue
+		}
+
+		bm.Name = strings.TrimSpace(rec["title"])
+
+		// Prefer the user's own note over the page excerpt
+		bm.Description = strings.TrimSpace(rec["note"])
+		if len(bm.Description) == 0 {
+			bm.Description = strings.TrimSpace(rec["excerpt"])
+		}
+
+		bm.Tags.AppendFromString(rec["tags"])
+		if folder := strings.TrimSpace(rec["folder"]); folder != "" && folder != "Unsorted" {
+			bm.Tags.Append(folder)
+		}
+
+		if t, err := time.Parse(time.RFC3339, rec["created"]); err == nil {
+			bm.WhenAdded = t
+			bm.LastUpdated = t
+		}
+
+		bookmarks = append(bookmarks, bm)
+	}
+
+	return bookmarks, nil
+}
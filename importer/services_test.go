@@ -0,0 +1,112 @@
+package importer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/importer"
+)
+
+const testPinboardFile = `[
+  {"href":"https://github.com/","description":"GitHub","extended":"Code hosting","meta":"x","hash":"y","time":"2019-01-01T10:00:00Z","shared":"yes","toread":"no","tags":"code git"},
+  {"href":"https://bbc.co.uk/","description":"BBC","extended":"","meta":"x","hash":"y","time":"2019-02-01T10:00:00Z","shared":"no","toread":"yes","tags":""}
+]`
+
+const testPocketHTMLFile = `<!DOCTYPE html>
+<html><head><title>Pocket Export</title></head><body>
+<h1>Unread</h1>
+<ul>
+<li><a href="https://github.com/" time_added="1546336800" tags="code,git">GitHub</a></li>
+</ul>
+<h1>Read Archive</h1>
+<ul>
+<li><a href="https://bbc.co.uk/" time_added="1549015200" tags="">BBC</a></li>
+</ul>
+</body></html>`
+
+const testPocketCSVFile = `title,url,time_added,tags,status
+GitHub,https://github.com/,1546336800,code|git,unread
+BBC,https://bbc.co.uk/,1549015200,,archive
+`
+
+const testRaindropFile = "\ufeff" + `id,title,note,excerpt,url,folder,tags,created,cover,highlights,favorite
+1,GitHub,Code hosting,Where the world builds software,https://github.com/,Dev,"code, git",2019-01-01T10:00:00.000Z,,,false
+2,BBC,,British news,https://bbc.co.uk/,Unsorted,,2019-02-01T10:00:00.000Z,,,false
+`
+
+func TestPinboardImport(t *testing.T) {
+	bms, err := importer.PinboardImporter{}.Import(strings.NewReader(testPinboardFile))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bms))
+
+	assert.Equal(t, "GitHub", bms[0].Name)
+	assert.Equal(t, "https://github.com/", bms[0].Url.String())
+	assert.Equal(t, "Code hosting", bms[0].Description)
+	assert.Equal(t, []string{"code", "git"}, bms[0].Tags.Tags)
+	assert.Equal(t, time.Date(2019, time.January, 1, 10, 0, 0, 0, time.UTC), bms[0].WhenAdded)
+
+	assert.Equal(t, []string{"toread"}, bms[1].Tags.Tags)
+}
+
+func TestPocketHTMLImport(t *testing.T) {
+	bms, err := importer.PocketImporter{}.Import(strings.NewReader(testPocketHTMLFile))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bms))
+
+	assert.Equal(t, "GitHub", bms[0].Name)
+	assert.Equal(t, "https://github.com/", bms[0].Url.String())
+	assert.Equal(t, []string{"code", "git", "toread"}, bms[0].Tags.Tags)
+	assert.True(t, time.Date(2019, time.January, 1, 10, 0, 0, 0, time.UTC).Equal(bms[0].WhenAdded))
+
+	assert.Equal(t, "BBC", bms[1].Name)
+	assert.Equal(t, 0, bms[1].Tags.Len())
+}
+
+func TestPocketCSVImport(t *testing.T) {
+	bms, err := importer.PocketImporter{}.Import(strings.NewReader(testPocketCSVFile))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bms))
+
+	assert.Equal(t, "GitHub", bms[0].Name)
+	assert.Equal(t, []string{"code", "git", "toread"}, bms[0].Tags.Tags)
+	assert.True(t, time.Date(2019, time.January, 1, 10, 0, 0, 0, time.UTC).Equal(bms[0].WhenAdded))
+
+	assert.Equal(t, "https://bbc.co.uk/", bms[1].Url.String())
+	assert.Equal(t, 0, bms[1].Tags.Len())
+}
+
+func TestRaindropImport(t *testing.T) {
+	bms, err := importer.RaindropImporter{}.Import(strings.NewReader(testRaindropFile))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bms))
+
+	assert.Equal(t, "GitHub", bms[0].Name)
+	assert.Equal(t, "Code hosting", bms[0].Description)
+	assert.Equal(t, []string{"Dev", "code", "git"}, bms[0].Tags.Tags)
+	assert.Equal(t, time.Date(2019, time.January, 1, 10, 0, 0, 0, time.UTC), bms[0].WhenAdded)
+
+	assert.Equal(t, "British news", bms[1].Description)
+	assert.Equal(t, 0, bms[1].Tags.Len())
+}
+
+func TestShaarliImportSkipsNotes(t *testing.T) {
+	const shaarli = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+<DT><A HREF="https://github.com/" ADD_DATE="1546336800" PRIVATE="0" TAGS="code,git">GitHub</A>
+<DD>Code hosting
+<DT><A HREF="?abc123" ADD_DATE="1546336800" PRIVATE="0" TAGS="">A note</A>
+<DD>Just some text
+</DL><p>`
+
+	i, err := importer.Get("shaarli")
+	assert.Nil(t, err)
+
+	bms, err := i.Import(strings.NewReader(shaarli))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(bms))
+	assert.Equal(t, []string{"code", "git"}, bms[0].Tags.Tags)
+	assert.Equal(t, "Code hosting", bms[0].Description)
+}
@@ -0,0 +1,397 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DanNixon/voile/db"
+)
+
+// Prefix of version 1 of the API
+const ApiPrefix = "/api/v1/"
+
+// Largest request body accepted by the API
+const maxApiRequestSize = 1 << 20
+
+// Editable fields of a bookmark, named as in the library file. Fields left out
+// of a PATCH request are not changed.
+type ApiBookmark struct {
+	Name        *string   `json:"title"`
+	Url         *string   `json:"uri"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+}
+
+type ApiTag struct {
+	Tag string `json:"tag"`
+	// Bookmarks with the tag
+	Count int `json:"count"`
+	// Bookmarks with the tag or any tag below it
+	Total int `json:"total"`
+}
+
+type ApiError struct {
+	Error string `json:"error"`
+}
+
+// Error that is reported with a specific HTTP status
+type statusError struct {
+	Status  int
+	Message string
+}
+
+func (e *statusError) Error() string {
+	return e.Message
+}
+
+func newStatusError(status int, format string, a ...interface{}) error {
+	return &statusError{status, fmt.Sprintf(format, a...)}
+}
+
+// Identifies a version of a bookmark for conditional requests
+func BookmarkETag(bm *db.Bookmark) string {
+	return fmt.Sprintf("\"%d-%d\"", bm.Number, bm.LastUpdated.UnixNano())
+}
+
+func (s *Server) handleApi(w http.ResponseWriter, r *http.Request) {
+	// Extensions and pages call the API from other origins, which is safe as
+	// requests are authenticated by the token rather than cookies
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "ETag, Location")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if len(s.Token) == 0 {
+		writeApiError(w, newStatusError(http.StatusForbidden, "API disabled, no token configured"))
+		return
+	}
+	if !s.authorised(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="voile"`)
+		writeApiError(w, newStatusError(http.StatusUnauthorized, "Missing or invalid API token"))
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, ApiPrefix)
+	switch {
+	case path == "bookmarks":
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			s.apiListBookmarks(w, r)
+		case http.MethodPost:
+			s.apiCreateBookmark(w, r)
+		default:
+			apiMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	case strings.HasPrefix(path, "bookmarks/"):
+		number, rest, err := parseBookmarkPath(path, "bookmarks/")
+		if err != nil || len(rest) > 0 {
+			writeApiError(w, newStatusError(http.StatusNotFound, "Not found"))
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			s.apiGetBookmark(w, r, number)
+		case http.MethodPut:
+			s.apiUpdateBookmark(w, r, number, false)
+		case http.MethodPatch:
+			s.apiUpdateBookmark(w, r, number, true)
+		case http.MethodDelete:
+			s.apiDeleteBookmark(w, r, number)
+		default:
+			apiMethodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+		}
+	case path == "save":
+		if r.Method != http.MethodPost {
+			apiMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		s.apiSave(w, r)
+	case path == "tags":
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			apiMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.apiListTags(w, r)
+	default:
+		writeApiError(w, newStatusError(http.StatusNotFound, "Not found"))
+	}
+}
+
+func (s *Server) authorised(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+func (s *Server) apiListBookmarks(w http.ResponseWriter, r *http.Request) {
+	bmks, err := s.read()
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	results, err := s.Filter(r.URL.Query(), &bmks)
+	if err != nil {
+		writeApiError(w, newStatusError(http.StatusBadRequest, "%s", err))
+		return
+	}
+
+	retVal := []db.Bookmark{}
+	for _, bm := range results {
+		retVal = append(retVal, *bm)
+	}
+
+	writeJson(w, http.StatusOK, retVal)
+}
+
+func (s *Server) apiGetBookmark(w http.ResponseWriter, r *http.Request, number int) {
+	bmks, err := s.read()
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	bm, err := bmks.GetByNumber(number)
+	if err != nil {
+		writeApiError(w, newStatusError(http.StatusNotFound, "%s", err))
+		return
+	}
+
+	etag := BookmarkETag(bm)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJson(w, http.StatusOK, bm)
+}
+
+func (s *Server) apiCreateBookmark(w http.ResponseWriter, r *http.Request) {
+	req, err := readApiBookmark(w, r)
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	var created db.Bookmark
+	err = s.update(func(bmks *db.BookmarkLibrary) error {
+		var bm db.Bookmark
+		if err := applyApiBookmark(&bm, req, false); err != nil {
+			return err
+		}
+		if existing, err := bmks.GetByUrl(bm.Url.String()); err == nil {
+			return newStatusError(http.StatusConflict, "%s is already bookmarked as %d", bm.Url.String(), existing.Number)
+		}
+		if err := s.prepare(&bm); err != nil {
+			return err
+		}
+
+		entry := bmks.NewEntry()
+		entry.Name = bm.Name
+		entry.Url = bm.Url
+		entry.Description = bm.Description
+		entry.Tags = bm.Tags
+
+		created = *entry
+		return nil
+	})
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", ApiPrefix+"bookmarks/"+strconv.Itoa(created.Number))
+	w.Header().Set("ETag", BookmarkETag(&created))
+	writeJson(w, http.StatusCreated, &created)
+}
+
+func (s *Server) apiUpdateBookmark(w http.ResponseWriter, r *http.Request, number int, partial bool) {
+	req, err := readApiBookmark(w, r)
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	var updated db.Bookmark
+	err = s.update(func(bmks *db.BookmarkLibrary) error {
+		bm, err := bmks.GetByNumber(number)
+		if err != nil {
+			return newStatusError(http.StatusNotFound, "%s", err)
+		}
+		if err = checkIfMatch(r, bm); err != nil {
+			return err
+		}
+
+		// Work on a copy so a failed request changes nothing
+		edited := *bm
+		if err = applyApiBookmark(&edited, req, partial); err != nil {
+			return err
+		}
+		if existing, err := bmks.GetByUrl(edited.Url.String()); err == nil && existing.Number != number {
+			return newStatusError(http.StatusConflict, "%s is already bookmarked as %d", edited.Url.String(), existing.Number)
+		}
+		if err = s.prepare(&edited); err != nil {
+			return err
+		}
+
+		edited.MarkUpdated()
+		*bm = edited
+
+		updated = edited
+		return nil
+	})
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", BookmarkETag(&updated))
+	writeJson(w, http.StatusOK, &updated)
+}
+
+func (s *Server) apiDeleteBookmark(w http.ResponseWriter, r *http.Request, number int) {
+	err := s.update(func(bmks *db.BookmarkLibrary) error {
+		bm, err := bmks.GetByNumber(number)
+		if err != nil {
+			return newStatusError(http.StatusNotFound, "%s", err)
+		}
+		if err = checkIfMatch(r, bm); err != nil {
+			return err
+		}
+
+		return bmks.DeleteByNumber(number)
+	})
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiListTags(w http.ResponseWriter, r *http.Request) {
+	bmks, err := s.read()
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	tags := bmks.GetAllTags()
+
+	retVal := []ApiTag{}
+	for _, t := range tags.Tags.Tags {
+		retVal = append(retVal, ApiTag{t, tags.Count[t], tags.Total[t]})
+	}
+
+	writeJson(w, http.StatusOK, retVal)
+}
+
+// Refuses to change a bookmark that changed since the client last saw it
+func checkIfMatch(r *http.Request, bm *db.Bookmark) error {
+	match := r.Header.Get("If-Match")
+	if len(match) == 0 || match == "*" {
+		return nil
+	}
+
+	for _, etag := range strings.Split(match, ",") {
+		if strings.TrimSpace(etag) == BookmarkETag(bm) {
+			return nil
+		}
+	}
+
+	return newStatusError(http.StatusPreconditionFailed, "Bookmark %d has changed", bm.Number)
+}
+
+func readApiBookmark(w http.ResponseWriter, r *http.Request) (ApiBookmark, error) {
+	var req ApiBookmark
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxApiRequestSize)).Decode(&req)
+	if err != nil {
+		return req, newStatusError(http.StatusBadRequest, "Invalid request: %s", err)
+	}
+
+	return req, nil
+}
+
+// Applies the fields of a request, a full update clears fields left out
+func applyApiBookmark(bm *db.Bookmark, req ApiBookmark, partial bool) error {
+	if req.Url != nil || !partial {
+		if req.Url == nil || len(strings.TrimSpace(*req.Url)) == 0 {
+			return newStatusError(http.StatusBadRequest, "A URL is required")
+		}
+
+		var u db.Url
+		if err := u.Parse(strings.TrimSpace(*req.Url)); err != nil {
+			return newStatusError(http.StatusBadRequest, "%s", err)
+		}
+		bm.Url = u
+	}
+
+	if req.Name != nil || !partial {
+		bm.Name = ""
+		if req.Name != nil {
+			bm.Name = strings.TrimSpace(*req.Name)
+		}
+	}
+
+	if req.Description != nil || !partial {
+		bm.Description = ""
+		if req.Description != nil {
+			bm.Description = strings.TrimSpace(*req.Description)
+		}
+	}
+
+	if req.Tags != nil || !partial {
+		bm.Tags.Clear()
+		if req.Tags != nil {
+			for _, t := range *req.Tags {
+				if t = strings.TrimSpace(t); len(t) > 0 && !bm.Tags.Contains(t) {
+					bm.Tags.Append(t)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(raw)
+	w.Write([]byte("\n"))
+}
+
+func writeApiError(w http.ResponseWriter, err error) {
+	raw, _ := json.Marshal(ApiError{err.Error()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(errorStatus(err, http.StatusInternalServerError))
+	w.Write(raw)
+	w.Write([]byte("\n"))
+}
+
+func apiMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeApiError(w, newStatusError(http.StatusMethodNotAllowed, "Method not allowed"))
+}
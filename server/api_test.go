@@ -0,0 +1,212 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/server"
+)
+
+const testToken = "secret"
+
+func newTestApiServer() (*server.Server, *db.MemoryStore) {
+	s, store := newTestServer()
+	s.Token = testToken
+	return s, store
+}
+
+func apiRequest(s http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func decodeBookmark(t *testing.T, w *httptest.ResponseRecorder) db.Bookmark {
+	var bm db.Bookmark
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &bm))
+	return bm
+}
+
+func TestApiRequiresToken(t *testing.T) {
+	s, _ := newTestServer()
+	w := apiRequest(s, http.MethodGet, "/api/v1/bookmarks", "", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	s.Token = "other"
+	w = apiRequest(s, http.MethodGet, "/api/v1/bookmarks", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"error"`)
+
+	w = apiRequest(s, http.MethodGet, "/api/v1/bookmarks", "", map[string]string{"Authorization": "Bearer other"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestApiListBookmarks(t *testing.T) {
+	s, _ := newTestApiServer()
+
+	w := apiRequest(s, http.MethodGet, "/api/v1/bookmarks", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var bms []db.Bookmark
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &bms))
+	assert.Equal(t, 3, len(bms))
+
+	w = apiRequest(s, http.MethodGet, "/api/v1/bookmarks?tag=lang&q=title:two", "", nil)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &bms))
+	assert.Equal(t, 1, len(bms))
+	assert.Equal(t, 2, bms[0].Number)
+
+	w = apiRequest(s, http.MethodGet, "/api/v1/bookmarks?tag=none", "", nil)
+	assert.Equal(t, "[]\n", w.Body.String())
+
+	w = apiRequest(s, http.MethodGet, "/api/v1/bookmarks?q=(", "", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestApiGetBookmark(t *testing.T) {
+	s, _ := newTestApiServer()
+
+	w := apiRequest(s, http.MethodGet, "/api/v1/bookmarks/3", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	bm := decodeBookmark(t, w)
+	assert.Equal(t, "https://golang.org", bm.Url.String())
+
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	w = apiRequest(s, http.MethodGet, "/api/v1/bookmarks/3", "", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = apiRequest(s, http.MethodGet, "/api/v1/bookmarks/9", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestApiCreateBookmark(t *testing.T) {
+	s, store := newTestApiServer()
+
+	w := apiRequest(s, http.MethodPost, "/api/v1/bookmarks",
+		`{"uri": "https://example.com", "title": "Example", "tags": ["test", " test", ""]}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v1/bookmarks/4", w.Header().Get("Location"))
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	bm := decodeBookmark(t, w)
+	assert.Equal(t, 4, bm.Number)
+	assert.Equal(t, []string{"test"}, bm.Tags.Tags)
+
+	bmks, _ := store.Load()
+	assert.Equal(t, 4, bmks.Len())
+
+	w = apiRequest(s, http.MethodPost, "/api/v1/bookmarks", `{"uri": "https://example.com"}`, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = apiRequest(s, http.MethodPost, "/api/v1/bookmarks", `{"title": "No URL"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = apiRequest(s, http.MethodPost, "/api/v1/bookmarks", `{`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestApiUpdateBookmark(t *testing.T) {
+	s, store := newTestApiServer()
+
+	w := apiRequest(s, http.MethodPatch, "/api/v1/bookmarks/2", `{"title": "BBC"}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	bm := decodeBookmark(t, w)
+	assert.Equal(t, "BBC", bm.Name)
+	assert.Equal(t, "https://bbc.co.uk", bm.Url.String())
+	assert.Equal(t, []string{"news", "lang/en"}, bm.Tags.Tags)
+
+	// Full update clears fields left out
+	w = apiRequest(s, http.MethodPut, "/api/v1/bookmarks/2", `{"uri": "https://www.bbc.co.uk"}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	bmks, _ := store.Load()
+	stored, _ := bmks.GetByNumber(2)
+	assert.Equal(t, "", stored.Name)
+	assert.Equal(t, "https://www.bbc.co.uk", stored.Url.String())
+	assert.Equal(t, 0, len(stored.Tags.Tags))
+
+	w = apiRequest(s, http.MethodPatch, "/api/v1/bookmarks/2", `{"uri": "https://github.com"}`, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = apiRequest(s, http.MethodPatch, "/api/v1/bookmarks/9", `{"title": "x"}`, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestApiIfMatch(t *testing.T) {
+	s, store := newTestApiServer()
+
+	w := apiRequest(s, http.MethodGet, "/api/v1/bookmarks/1", "", nil)
+	etag := w.Header().Get("ETag")
+
+	w = apiRequest(s, http.MethodPatch, "/api/v1/bookmarks/1", `{"title": "first"}`, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// Stale version is refused
+	w = apiRequest(s, http.MethodPatch, "/api/v1/bookmarks/1", `{"title": "again"}`, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = apiRequest(s, http.MethodDelete, "/api/v1/bookmarks/1", "", map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	bmks, _ := store.Load()
+	bm, _ := bmks.GetByNumber(1)
+	assert.Equal(t, "first", bm.Name)
+}
+
+func TestApiDeleteBookmark(t *testing.T) {
+	s, store := newTestApiServer()
+
+	w := apiRequest(s, http.MethodDelete, "/api/v1/bookmarks/1", "", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	bmks, _ := store.Load()
+	assert.Equal(t, 2, bmks.Len())
+
+	w = apiRequest(s, http.MethodDelete, "/api/v1/bookmarks/1", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestApiCrossOriginAllowed(t *testing.T) {
+	s, _ := newTestApiServer()
+
+	w := apiRequest(s, http.MethodDelete, "/api/v1/bookmarks/1", "", map[string]string{"Origin": "moz-extension://abc"})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestApiTags(t *testing.T) {
+	s, _ := newTestApiServer()
+
+	w := apiRequest(s, http.MethodGet, "/api/v1/tags", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tags []server.ApiTag
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &tags))
+	assert.Contains(t, tags, server.ApiTag{Tag: "software", Count: 2, Total: 2})
+	assert.Contains(t, tags, server.ApiTag{Tag: "lang/go", Count: 1, Total: 1})
+}
+
+func TestApiNotFound(t *testing.T) {
+	s, _ := newTestApiServer()
+
+	assert.Equal(t, http.StatusNotFound, apiRequest(s, http.MethodGet, "/api/v1/nothing", "", nil).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, apiRequest(s, http.MethodPut, "/api/v1/bookmarks", "", nil).Code)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Cookie holding a web interface session
+const sessionCookieName = "voile_session"
+
+const loginDisabled = "Logging in is disabled, no token configured"
+
+type loginPage struct {
+	Next  string
+	Error string
+}
+
+// The session is derived from the token, so changing the token ends every
+// session
+func (s *Server) sessionValue() string {
+	mac := hmac.New(sha256.New, []byte(s.Token))
+	mac.Write([]byte("voile web session"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) hasSession(r *http.Request) bool {
+	if len(s.Token) == 0 {
+		return false
+	}
+
+	c, err := r.Cookie(sessionCookieName)
+	return err == nil && hmac.Equal([]byte(c.Value), []byte(s.sessionValue()))
+}
+
+func (s *Server) setSession(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    s.sessionValue(),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Sends browsers without a session to log in, the token may be sent instead
+func (s *Server) checkSession(w http.ResponseWriter, r *http.Request) bool {
+	if s.hasSession(r) || (len(s.Token) > 0 && s.authorised(r)) {
+		return true
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	} else {
+		http.Error(w, "Log in first", http.StatusUnauthorized)
+	}
+	return false
+}
+
+// Starts a session for whoever knows the token
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		page := loginPage{Next: localPath(r.URL.Query().Get("next"))}
+		if len(s.Token) == 0 {
+			page.Error = loginDisabled
+		}
+		render(w, http.StatusOK, loginTemplate, page)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxApiRequestSize)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := loginPage{Next: localPath(r.PostForm.Get("next"))}
+	if len(s.Token) == 0 {
+		page.Error = loginDisabled
+		render(w, http.StatusForbidden, loginTemplate, page)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.PostForm.Get("token")), []byte(s.Token)) != 1 {
+		page.Error = "Invalid token"
+		render(w, http.StatusUnauthorized, loginTemplate, page)
+		return
+	}
+
+	s.setSession(w)
+	http.Redirect(w, r, page.Next, http.StatusSeeOther)
+}
+
+// Refuses requests for other host names, which a page on another site can
+// send after pointing its own name at this server (DNS rebinding)
+func (s *Server) isAllowedHost(r *http.Request) bool {
+	if len(s.AllowedHosts) == 0 {
+		return true
+	}
+
+	host := strings.ToLower(r.Host)
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		// The default port is left out
+		port := "80"
+		if r.TLS != nil {
+			port = "443"
+		}
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+
+	for _, h := range s.AllowedHosts {
+		if strings.ToLower(h) == host {
+			return true
+		}
+	}
+	return false
+}
+
+func isSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		origin = r.Header.Get("Referer")
+	}
+	if len(origin) == 0 {
+		// Browsers send at least one of them with forms, so this is not one
+		// of our pages
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// Only redirects within the server after logging in
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,107 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	s, _ := newTestServer()
+	s.AllowedHosts = []string{"localhost:8080", "example.com:80"}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = "localhost:8080"
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The default port is left out of the Host header
+	w = get(s, "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Another name pointed at the server
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = "evil.example.com:8080"
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusMisdirectedRequest, w.Code)
+	assert.NotContains(t, w.Body.String(), "https://github.com")
+
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/bookmarks", nil)
+	r.Host = "evil.example.com:8080"
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusMisdirectedRequest, w.Code)
+}
+
+func TestRequireLogin(t *testing.T) {
+	s, store := newTestServer()
+	s.Token = "secret"
+	s.RequireLogin = true
+
+	w := get(s, "/tags?x=1")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/login?next=%2Ftags%3Fx%3D1", w.Header().Get("Location"))
+
+	w = post(s, "/bookmarks/1/delete", url.Values{})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	bmks, _ := store.Load()
+	assert.Equal(t, 3, bmks.Len())
+
+	w = get(s, "/login?next=%2Ftags")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `name="next" value="/tags"`)
+
+	w = post(s, "/login", url.Values{"token": {"wrong"}, "next": {"/tags"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid token")
+	assert.Equal(t, 0, len(w.Result().Cookies()))
+
+	w = post(s, "/login", url.Values{"token": {"secret"}, "next": {"/tags"}})
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/tags", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	assert.NotContains(t, cookies[0].Value, "secret")
+
+	w = get(s, "/tags", cookies[0])
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = post(s, "/bookmarks/1/delete", url.Values{}, cookies[0])
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	bmks, _ = store.Load()
+	assert.Equal(t, 2, bmks.Len())
+
+	// Changing the token ends the session
+	s.Token = "changed"
+	w = get(s, "/tags", cookies[0])
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+}
+
+func TestLoginRedirectStaysLocal(t *testing.T) {
+	s, _ := newTestServer()
+	s.Token = "secret"
+
+	for _, next := range []string{"https://evil.example.com", "//evil.example.com", ""} {
+		w := post(s, "/login", url.Values{"token": {"secret"}, "next": {next}})
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "/", w.Header().Get("Location"))
+	}
+}
+
+func TestLoginWithoutToken(t *testing.T) {
+	s, _ := newTestServer()
+
+	r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("token="))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Origin", "http://example.com")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, 0, len(w.Result().Cookies()))
+}
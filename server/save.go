@@ -0,0 +1,240 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/DanNixon/voile/db"
+)
+
+// A page saved from a browser, as posted by the bookmarklet or an extension
+type SavedPage struct {
+	Url   string `json:"url"`
+	Title string `json:"title"`
+	// Usually the text selected on the page
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// Adds a saved page to the library, which is locked
+type AddFunc func(bmks *db.BookmarkLibrary, page SavedPage) (*db.Bookmark, error)
+
+// Does anything slow needed to add a saved page, such as fetching it, before
+// the library is locked and returns how to add it
+type FetchFunc func(page SavedPage) (AddFunc, error)
+
+type savePage struct {
+	Page  SavedPage
+	Tags  string
+	Saved *db.Bookmark
+	Error string
+}
+
+type bookmarkletPage struct {
+	Bookmarklet template.URL
+}
+
+// Adds the page as given without fetching anything
+func DefaultFetch(page SavedPage) (AddFunc, error) {
+	return DefaultAdd, nil
+}
+
+// Adds the page as given
+func DefaultAdd(bmks *db.BookmarkLibrary, page SavedPage) (*db.Bookmark, error) {
+	var u db.Url
+	if err := u.Parse(page.Url); err != nil {
+		return nil, err
+	}
+
+	bm := bmks.NewEntry()
+	bm.Url = u
+	bm.Name = page.Title
+	bm.Description = page.Description
+	for _, t := range page.Tags {
+		bm.Tags.Append(t)
+	}
+
+	return bm, nil
+}
+
+// Saves a page unless it is already bookmarked
+func (s *Server) save(page SavedPage) (db.Bookmark, error) {
+	page.Url = strings.TrimSpace(page.Url)
+	page.Title = strings.TrimSpace(page.Title)
+	page.Description = strings.TrimSpace(strings.Replace(page.Description, "\r\n", "\n", -1))
+	page.Tags = cleanTags(page.Tags)
+
+	if len(page.Url) == 0 {
+		return db.Bookmark{}, newStatusError(http.StatusBadRequest, "A URL is required")
+	}
+
+	// Avoid fetching pages already bookmarked, checked again once locked
+	bmks, err := s.read()
+	if err != nil {
+		return db.Bookmark{}, err
+	}
+	if err = alreadySaved(&bmks, page); err != nil {
+		return db.Bookmark{}, err
+	}
+
+	add, err := s.Fetch(page)
+	if err != nil {
+		return db.Bookmark{}, newStatusError(http.StatusBadRequest, "%s", err)
+	}
+
+	var saved db.Bookmark
+	err = s.update(func(bmks *db.BookmarkLibrary) error {
+		if err := alreadySaved(bmks, page); err != nil {
+			return err
+		}
+
+		bm, err := add(bmks, page)
+		if err != nil {
+			return newStatusError(http.StatusBadRequest, "%s", err)
+		}
+		if err = s.prepare(bm); err != nil {
+			return err
+		}
+
+		saved = *bm
+		return nil
+	})
+
+	return saved, err
+}
+
+func alreadySaved(bmks *db.BookmarkLibrary, page SavedPage) error {
+	if existing, err := bmks.GetByUrl(page.Url); err == nil {
+		return newStatusError(http.StatusConflict, "%s is already bookmarked as %d", page.Url, existing.Number)
+	}
+	return nil
+}
+
+// Saves a page posted as JSON or a form, with tags comma separated
+func (s *Server) apiSave(w http.ResponseWriter, r *http.Request) {
+	var page SavedPage
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxApiRequestSize)).Decode(&page)
+		if err != nil {
+			writeApiError(w, newStatusError(http.StatusBadRequest, "Invalid request: %s", err))
+			return
+		}
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, maxApiRequestSize)
+		if err := r.ParseForm(); err != nil {
+			writeApiError(w, newStatusError(http.StatusBadRequest, "Invalid request: %s", err))
+			return
+		}
+		page = savedPageFromValues(r.PostForm)
+	}
+
+	bm, err := s.save(page)
+	if err != nil {
+		writeApiError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", ApiPrefix+"bookmarks/"+strconv.Itoa(bm.Number))
+	w.Header().Set("ETag", BookmarkETag(&bm))
+	writeJson(w, http.StatusCreated, &bm)
+}
+
+// Form opened by the bookmarklet, filled in from the query. Pages can be saved
+// by any site the bookmarklet is used on, so this needs a session even when
+// the rest of the web interface does not.
+func (s *Server) handleSave(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if !s.checkSession(w, r) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		page := savedPageFromValues(r.URL.Query())
+		render(w, http.StatusOK, saveTemplate, savePage{Page: page, Tags: r.URL.Query().Get("tags")})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxApiRequestSize)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := savedPageFromValues(r.PostForm)
+	bm, err := s.save(page)
+	if err != nil {
+		render(w, errorStatus(err, http.StatusInternalServerError), saveTemplate, savePage{Page: page, Tags: r.PostForm.Get("tags"), Error: err.Error()})
+		return
+	}
+
+	render(w, http.StatusOK, saveTemplate, savePage{Page: page, Saved: &bm})
+}
+
+// Logging in to get the bookmarklet starts the session used by the save form
+func (s *Server) handleBookmarklet(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	if !s.checkSession(w, r) {
+		return
+	}
+
+	render(w, http.StatusOK, bookmarkletTemplate, bookmarkletPage{Bookmarklet: template.URL(Bookmarklet(serverUrl(r)))})
+}
+
+// Javascript that opens the save form for the current page of a browser
+func Bookmarklet(base string) string {
+	return fmt.Sprintf("javascript:(function(){"+
+		"var e=encodeURIComponent;"+
+		"window.open(%s+'/save?url='+e(location.href)+'&title='+e(document.title)+'&description='+e(String(window.getSelection())),"+
+		"'voile','width=640,height=560');"+
+		"})()", strconv.Quote(base))
+}
+
+// Reads a saved page from query parameters or a posted form
+func savedPageFromValues(values url.Values) SavedPage {
+	return SavedPage{
+		Url:         values.Get("url"),
+		Title:       values.Get("title"),
+		Description: values.Get("description"),
+		Tags:        strings.Split(values.Get("tags"), ","),
+	}
+}
+
+func cleanTags(tags []string) []string {
+	var retVal []string
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); len(t) > 0 && !containsString(retVal, t) {
+			retVal = append(retVal, t)
+		}
+	}
+	return retVal
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Address the request was sent to, as seen by the browser
+func serverUrl(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
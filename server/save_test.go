@@ -0,0 +1,220 @@
+package server_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/server"
+)
+
+func TestApiSaveJson(t *testing.T) {
+	s, store := newTestApiServer()
+
+	w := apiRequest(s, http.MethodPost, "/api/v1/save",
+		`{"url": "https://example.com/page", "title": "A page", "description": "Selected text", "tags": ["read", "read", " later "]}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v1/bookmarks/4", w.Header().Get("Location"))
+
+	bmks, _ := store.Load()
+	bm, err := bmks.GetByNumber(4)
+	assert.Nil(t, err)
+	assert.Equal(t, "A page", bm.Name)
+	assert.Equal(t, "https://example.com/page", bm.Url.String())
+	assert.Equal(t, "Selected text", bm.Description)
+	assert.Equal(t, []string{"later", "read"}, bm.Tags.Tags)
+
+	w = apiRequest(s, http.MethodPost, "/api/v1/save", `{"url": "https://example.com/page"}`, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "already bookmarked as 4")
+}
+
+func TestApiSaveForm(t *testing.T) {
+	s, store := newTestApiServer()
+
+	form := url.Values{"url": {"https://example.com"}, "title": {"Example"}, "tags": {"a, b"}}
+	w := apiRequest(s, http.MethodPost, "/api/v1/save", form.Encode(),
+		map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	bmks, _ := store.Load()
+	bm, _ := bmks.GetByNumber(4)
+	assert.Equal(t, "Example", bm.Name)
+	assert.Equal(t, []string{"a", "b"}, bm.Tags.Tags)
+}
+
+func TestApiSaveRequiresToken(t *testing.T) {
+	s, _ := newTestApiServer()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/save", strings.NewReader(`{"url": "https://example.com"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestApiPreflight(t *testing.T) {
+	s, _ := newTestApiServer()
+
+	r := httptest.NewRequest(http.MethodOptions, "/api/v1/save", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+}
+
+func TestSaveForm(t *testing.T) {
+	s, store := newTestServer()
+	session := login(t, s)
+
+	w := get(s, "/save?url=https%3A%2F%2Fexample.com&title=Example&description=Some+text", session)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `value="https://example.com"`)
+	assert.Contains(t, w.Body.String(), `value="Example"`)
+	assert.Contains(t, w.Body.String(), `>Some text</textarea>`)
+
+	w = post(s, "/save", url.Values{"url": {"https://example.com"}, "title": {"Example"}, "tags": {"x"}}, session)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Saved bookmark 4")
+
+	bmks, _ := store.Load()
+	bm, _ := bmks.GetByNumber(4)
+	assert.Equal(t, []string{"x"}, bm.Tags.Tags)
+
+	w = post(s, "/save", url.Values{"url": {"https://example.com"}}, session)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestSaveFormRequiresSession(t *testing.T) {
+	s, store := newTestServer()
+
+	// Disabled without a token
+	w := post(s, "/save", url.Values{"url": {"https://example.com"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	login(t, s)
+	w = get(s, "/save?url=https%3A%2F%2Fexample.com")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/login?next=%2Fsave%3Furl%3Dhttps%253A%252F%252Fexample.com", w.Header().Get("Location"))
+
+	w = post(s, "/save", url.Values{"url": {"https://example.com"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	bmks, _ := store.Load()
+	assert.Equal(t, 3, bmks.Len())
+
+	// The token can be sent instead
+	r := httptest.NewRequest(http.MethodPost, "/save", strings.NewReader("url=https%3A%2F%2Fexample.com"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Origin", "http://example.com")
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	bmks, _ = store.Load()
+	assert.Equal(t, 4, bmks.Len())
+}
+
+func TestSaveFormTooLarge(t *testing.T) {
+	s, store := newTestServer()
+	session := login(t, s)
+
+	w := post(s, "/save", url.Values{"url": {"https://example.com"}, "description": {strings.Repeat("x", 2<<20)}}, session)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	bmks, _ := store.Load()
+	assert.Equal(t, 3, bmks.Len())
+}
+
+func TestSaveUsesFetch(t *testing.T) {
+	s, store := newTestServer()
+	session := login(t, s)
+
+	s.Fetch = func(page server.SavedPage) (server.AddFunc, error) {
+		// Other requests are served while fetching
+		done := make(chan int)
+		go func() {
+			store.Lock()
+			store.Unlock()
+			done <- get(s, "/").Code
+		}()
+		select {
+		case code := <-done:
+			assert.Equal(t, http.StatusOK, code)
+		case <-time.After(time.Second):
+			t.Error("Library locked while fetching")
+		}
+
+		return func(bmks *db.BookmarkLibrary, page server.SavedPage) (*db.Bookmark, error) {
+			bm, err := server.DefaultAdd(bmks, page)
+			bm.Name = "Fetched"
+			return bm, err
+		}, nil
+	}
+
+	w := post(s, "/save", url.Values{"url": {"https://example.com"}}, session)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	bmks, _ := store.Load()
+	bm, _ := bmks.GetByNumber(4)
+	assert.Equal(t, "Fetched", bm.Name)
+
+	// Pages already bookmarked are not fetched
+	fetched := false
+	s.Fetch = func(page server.SavedPage) (server.AddFunc, error) {
+		fetched = true
+		return server.DefaultAdd, nil
+	}
+
+	w = post(s, "/save", url.Values{"url": {"https://example.com"}}, session)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.False(t, fetched)
+
+	// Nothing is saved if fetching or adding fails
+	s.Fetch = func(page server.SavedPage) (server.AddFunc, error) {
+		return nil, errors.New("Could not fetch page")
+	}
+
+	w = post(s, "/save", url.Values{"url": {"https://example.org"}}, session)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Could not fetch page")
+
+	s.Fetch = func(page server.SavedPage) (server.AddFunc, error) {
+		return func(bmks *db.BookmarkLibrary, page server.SavedPage) (*db.Bookmark, error) {
+			bmks.NewEntry()
+			return nil, errors.New("Could not add page")
+		}, nil
+	}
+
+	w = post(s, "/save", url.Values{"url": {"https://example.org"}}, session)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Could not add page")
+
+	bmks, _ = store.Load()
+	assert.Equal(t, 4, bmks.Len())
+}
+
+func TestBookmarklet(t *testing.T) {
+	s, _ := newTestServer()
+
+	w := get(s, "/bookmarklet")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+
+	w = get(s, "/bookmarklet", login(t, s))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `href="javascript:`)
+
+	assert.Contains(t, server.Bookmarklet("http://localhost:8080"), `"http://localhost:8080"+'/save?url='`)
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DanNixon/voile/db"
+)
+
+// Selects the bookmarks matching a request's query parameters, in the order
+// they should be presented
+type FilterFunc func(params url.Values, bmks *db.BookmarkLibrary) ([]*db.Bookmark, error)
+
+// Serves a bookmark library over HTTP
+type Server struct {
+	Store  db.Store
+	Filter FilterFunc
+
+	// Required by API requests, the API is disabled without one
+	Token string
+	// Host names, with ports, requests may be sent to, any if empty
+	AllowedHosts []string
+	// Whether the web interface needs a session, started by logging in with
+	// the token
+	RequireLogin bool
+
+	// Prepares to add pages saved from a browser
+	Fetch FetchFunc
+
+	// Called for every bookmark created or edited, before it is saved. Failing
+	// stops the request with an internal server error.
+	Prepare func(bm *db.Bookmark) error
+	// Called after the library is saved with the lock still held, e.g. to
+	// commit it to Git
+	Saved func() error
+
+	mux *http.ServeMux
+	// The store lock does not exclude other requests in the same process
+	mutex sync.Mutex
+}
+
+func NewServer(store db.Store) *Server {
+	s := &Server{
+		Store:  store,
+		Filter: DefaultFilter,
+		Fetch:  DefaultFetch,
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/", s.handleIndex)
+	s.mux.HandleFunc("/tags", s.handleTags)
+	s.mux.HandleFunc("/bookmarks/", s.handleBookmark)
+	s.mux.HandleFunc("/save", s.handleSave)
+	s.mux.HandleFunc("/bookmarklet", s.handleBookmarklet)
+	s.mux.HandleFunc("/login", s.handleLogin)
+	s.mux.HandleFunc(ApiPrefix, s.handleApi)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.isAllowedHost(r) {
+		http.Error(w, "Unknown host", http.StatusMisdirectedRequest)
+		return
+	}
+
+	// Only accept changes from pages served by this server, the API instead
+	// requires a token
+	api := strings.HasPrefix(r.URL.Path, ApiPrefix)
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if !read && !api && !isSameOrigin(r) {
+		http.Error(w, "Cross origin request refused", http.StatusForbidden)
+		return
+	}
+
+	if s.RequireLogin && !api && r.URL.Path != "/login" && !s.checkSession(w, r) {
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// Filters by a boolean query in q and tags in tag
+func DefaultFilter(params url.Values, bmks *db.BookmarkLibrary) ([]*db.Bookmark, error) {
+	var query db.Predicate
+	if q := strings.TrimSpace(params.Get("q")); len(q) > 0 {
+		var err error
+		query, err = db.ParseQuery(q)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var results []*db.Bookmark
+	for i := range bmks.Bookmarks {
+		bm := &(bmks.Bookmarks[i])
+		if query != nil && !query(bm) {
+			continue
+		}
+		if tags := params["tag"]; len(tags) > 0 && !bm.Tags.HasAllTags(tags) {
+			continue
+		}
+		results = append(results, bm)
+	}
+
+	return results, nil
+}
+
+// Loads the library for reading
+func (s *Server) read() (db.BookmarkLibrary, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.Store.Load()
+}
+
+// Loads the library with the lock held and saves it if modify succeeds
+func (s *Server) update(modify func(bmks *db.BookmarkLibrary) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.Store.Lock()
+	if err != nil {
+		return err
+	}
+	defer s.Store.Unlock()
+
+	bmks, err := s.Store.Load()
+	if err != nil {
+		return err
+	}
+
+	if err = modify(&bmks); err != nil {
+		return err
+	}
+
+	if err = s.Store.Save(&bmks); err != nil {
+		return err
+	}
+
+	if s.Saved != nil {
+		return s.Saved()
+	}
+	return nil
+}
+
+func (s *Server) prepare(bm *db.Bookmark) error {
+	if s.Prepare == nil {
+		return nil
+	}
+
+	if err := s.Prepare(bm); err != nil {
+		return newStatusError(http.StatusInternalServerError, "%s", err)
+	}
+	return nil
+}
+
+// Status to respond with for an error, for errors without their own status
+func errorStatus(err error, status int) int {
+	if se, ok := err.(*statusError); ok {
+		return se.Status
+	}
+	return status
+}
+
+// Applies the fields of a submitted bookmark form
+func updateFromForm(bm *db.Bookmark, r *http.Request) error {
+	name := strings.TrimSpace(r.PostFormValue("name"))
+	rawUrl := strings.TrimSpace(r.PostFormValue("url"))
+	if len(rawUrl) == 0 {
+		return errors.New("A URL is required")
+	}
+
+	var u db.Url
+	if err := u.Parse(rawUrl); err != nil {
+		return err
+	}
+
+	bm.Name = name
+	bm.Url = u
+	bm.Description = strings.TrimSpace(strings.Replace(r.PostFormValue("description"), "\r\n", "\n", -1))
+
+	bm.Tags.Clear()
+	for _, t := range strings.Split(r.PostFormValue("tags"), ",") {
+		if t = strings.TrimSpace(t); len(t) > 0 && !bm.Tags.Contains(t) {
+			bm.Tags.Append(t)
+		}
+	}
+
+	bm.MarkUpdated()
+	return nil
+}
+
+// Splits a path below prefix into a bookmark number and the rest of the path
+func parseBookmarkPath(path, prefix string) (int, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, prefix), "/", 2)
+
+	number, err := strconv.Atoi(parts[0])
+	if err != nil || number < 0 {
+		return 0, "", errors.New(fmt.Sprintf("Invalid bookmark number \"%s\"", parts[0]))
+	}
+
+	rest := ""
+	if len(parts) > 1 {
+		rest = parts[1]
+	}
+
+	return number, rest, nil
+}
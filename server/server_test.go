@@ -0,0 +1,246 @@
+package server_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/db"
+	"github.com/DanNixon/voile/server"
+)
+
+func newTestServer() (*server.Server, *db.MemoryStore) {
+	store := &db.MemoryStore{}
+
+	var bmks db.BookmarkLibrary
+	for _, b := range []struct {
+		name, url string
+		tags      []string
+	}{
+		{"one", "https://github.com", []string{"software"}},
+		{"two", "https://bbc.co.uk", []string{"news", "lang/en"}},
+		{"three", "https://golang.org", []string{"software", "lang/go"}},
+	} {
+		bm := bmks.NewEntry()
+		bm.Name = b.name
+		bm.Url.Parse(b.url)
+		bm.Tags.Tags = b.tags
+	}
+	store.Save(&bmks)
+
+	return server.NewServer(store), store
+}
+
+// Sets the token of the server and logs in with it
+func login(t *testing.T, s *server.Server) *http.Cookie {
+	s.Token = "secret"
+
+	w := post(s, "/login", url.Values{"token": {"secret"}})
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+
+	cookies := w.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	return cookies[0]
+}
+
+func get(s http.Handler, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func post(s http.Handler, target string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// As sent by forms on pages of the server
+	r.Header.Set("Origin", "http://"+r.Host)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestIndexListsBookmarks(t *testing.T) {
+	s, _ := newTestServer()
+
+	w := get(s, "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "https://github.com")
+	assert.Contains(t, w.Body.String(), "https://bbc.co.uk")
+	assert.Contains(t, w.Body.String(), "https://golang.org")
+}
+
+func TestIndexFilters(t *testing.T) {
+	s, _ := newTestServer()
+
+	w := get(s, "/?tag=lang")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "https://github.com")
+	assert.Contains(t, w.Body.String(), "https://bbc.co.uk")
+	assert.Contains(t, w.Body.String(), "https://golang.org")
+
+	w = get(s, "/?q=title:three")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "https://bbc.co.uk")
+	assert.Contains(t, w.Body.String(), "https://golang.org")
+
+	w = get(s, "/?q=(")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestIndexNotFound(t *testing.T) {
+	s, _ := newTestServer()
+	assert.Equal(t, http.StatusNotFound, get(s, "/nothing").Code)
+}
+
+func TestTags(t *testing.T) {
+	s, _ := newTestServer()
+
+	w := get(s, "/tags")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `href="/?tag=lang%2fgo"`)
+	assert.Contains(t, w.Body.String(), `>lang</a> (2)`)
+	assert.Contains(t, w.Body.String(), `>go</a> (1)`)
+}
+
+func TestEdit(t *testing.T) {
+	s, store := newTestServer()
+
+	w := get(s, "/bookmarks/2/edit")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `value="https://bbc.co.uk"`)
+	assert.Contains(t, w.Body.String(), `value="news, lang/en"`)
+
+	w = post(s, "/bookmarks/2/edit", url.Values{
+		"name":        {"BBC"},
+		"url":         {"https://www.bbc.co.uk"},
+		"description": {"Line one\r\nLine two"},
+		"tags":        {"news, uk ,"},
+	})
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+
+	bmks, _ := store.Load()
+	bm, _ := bmks.GetByNumber(2)
+	assert.Equal(t, "BBC", bm.Name)
+	assert.Equal(t, "https://www.bbc.co.uk", bm.Url.String())
+	assert.Equal(t, "Line one\nLine two", bm.Description)
+	assert.Equal(t, []string{"news", "uk"}, bm.Tags.Tags)
+}
+
+func TestEditExistingUrl(t *testing.T) {
+	s, store := newTestServer()
+
+	w := post(s, "/bookmarks/2/edit", url.Values{"url": {"https://github.com"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "already bookmarked as 1")
+
+	bmks, _ := store.Load()
+	bm, _ := bmks.GetByNumber(2)
+	assert.Equal(t, "https://bbc.co.uk", bm.Url.String())
+}
+
+func TestEditMissing(t *testing.T) {
+	s, _ := newTestServer()
+	assert.Equal(t, http.StatusNotFound, get(s, "/bookmarks/9/edit").Code)
+	assert.Equal(t, http.StatusNotFound, get(s, "/bookmarks/x/edit").Code)
+}
+
+func TestNew(t *testing.T) {
+	s, store := newTestServer()
+
+	prepared := 0
+	saved := 0
+	s.Prepare = func(bm *db.Bookmark) error { prepared++; return nil }
+	s.Saved = func() error { saved++; return nil }
+
+	w := post(s, "/bookmarks/new", url.Values{"name": {"four"}, "url": {"https://example.com"}, "tags": {"test"}})
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, 1, prepared)
+	assert.Equal(t, 1, saved)
+
+	bmks, _ := store.Load()
+	bm, err := bmks.GetByNumber(4)
+	assert.Nil(t, err)
+	assert.Equal(t, "four", bm.Name)
+	assert.Equal(t, []string{"test"}, bm.Tags.Tags)
+
+	w = post(s, "/bookmarks/new", url.Values{"name": {"five"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 1, saved)
+}
+
+func TestPrepareFailure(t *testing.T) {
+	s, store := newTestApiServer()
+	s.Prepare = func(bm *db.Bookmark) error { return errors.New("No aliases") }
+
+	w := post(s, "/bookmarks/new", url.Values{"name": {"four"}, "url": {"https://example.com"}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "No aliases")
+
+	w = post(s, "/bookmarks/1/edit", url.Values{"name": {"one"}, "url": {"https://example.com"}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = apiRequest(s, http.MethodPost, "/api/v1/bookmarks", `{"uri": "https://example.com"}`, nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// Nothing is saved
+	bmks, _ := store.Load()
+	assert.Equal(t, 3, bmks.Len())
+	bm, _ := bmks.GetByNumber(1)
+	assert.NotEqual(t, "https://example.com", bm.Url.String())
+}
+
+func TestDelete(t *testing.T) {
+	s, store := newTestServer()
+
+	assert.Equal(t, http.StatusMethodNotAllowed, get(s, "/bookmarks/1/delete").Code)
+
+	w := post(s, "/bookmarks/1/delete", url.Values{})
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+
+	bmks, _ := store.Load()
+	assert.Equal(t, 2, bmks.Len())
+	_, err := bmks.GetByNumber(1)
+	assert.NotNil(t, err)
+
+	w = post(s, "/bookmarks/1/delete", url.Values{})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCrossOriginRefused(t *testing.T) {
+	s, store := newTestServer()
+
+	r := httptest.NewRequest(http.MethodPost, "/bookmarks/1/delete", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	bmks, _ := store.Load()
+	assert.Equal(t, 3, bmks.Len())
+}
+
+func TestCrossOriginRefusedWithoutOrigin(t *testing.T) {
+	s, store := newTestServer()
+
+	r := httptest.NewRequest(http.MethodPost, "/bookmarks/1/delete", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	bmks, _ := store.Load()
+	assert.Equal(t, 3, bmks.Len())
+}
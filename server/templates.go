@@ -0,0 +1,126 @@
+package server
+
+import (
+	"html/template"
+)
+
+const layoutTemplateString = `{{define "layout"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>voile</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 1em auto; padding: 0 1em; }
+nav a { margin-right: 1em; }
+.bookmark { margin: 1em 0; }
+.bookmark .url { color: #806000; word-break: break-all; }
+.bookmark .desc { white-space: pre-wrap; }
+.tag { color: #0050a0; margin-right: 0.5em; }
+.error { color: #c00000; }
+form.inline { display: inline; }
+label { display: block; margin-top: 0.5em; }
+input[type=text], input[type=password], textarea { width: 100%; box-sizing: border-box; }
+</style>
+</head>
+<body>
+<nav><a href="/">Bookmarks</a><a href="/tags">Tags</a><a href="/bookmarks/new">Add</a><a href="/bookmarklet">Bookmarklet</a></nav>
+{{template "content" .}}
+</body>
+</html>{{end}}`
+
+const indexTemplateString = `{{define "content"}}
+<form method="get" action="/">
+<input type="text" name="q" value="{{.Query}}" placeholder="tag:news AND title:bbc">
+{{range .Tags}}<input type="hidden" name="tag" value="{{.}}">{{end}}
+<button type="submit">Search</button>
+</form>
+{{if .Tags}}<p>Tagged {{range .Tags}}<span class="tag">{{.}}</span>{{end}} <a href="/">clear</a></p>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<p>{{len .Bookmarks}} bookmark(s)</p>
+{{range .Bookmarks}}
+<div class="bookmark">
+<div><a href="{{.Url.String}}">{{if .Name}}{{.Name}}{{else}}{{.Url.String}}{{end}}</a> [{{.Number}}]</div>
+<div class="url">{{.Url.String}}</div>
+{{if .Description}}<div class="desc">{{.Description}}</div>{{end}}
+<div>{{range .Tags.Tags}}<a class="tag" href="/?tag={{.}}">{{.}}</a>{{end}}</div>
+<div>
+<a href="/bookmarks/{{.Number}}/edit">edit</a>
+<form class="inline" method="post" action="/bookmarks/{{.Number}}/delete" onsubmit="return confirm('Really remove bookmark {{.Number}}?')">
+<button type="submit">delete</button>
+</form>
+</div>
+</div>
+{{end}}
+{{end}}`
+
+const tagsTemplateString = `{{define "content"}}
+{{if .Tags}}{{template "tree" .Tags}}{{else}}<p>No tags.</p>{{end}}
+{{end}}
+{{define "tree"}}<ul>
+{{range .}}<li><a class="tag" href="/?tag={{.Tag}}">{{.Name}}</a> ({{.Count}}){{if .Children}}{{template "tree" .Children}}{{end}}</li>
+{{end}}</ul>{{end}}`
+
+const editTemplateString = `{{define "content"}}
+<h1>{{if .Bookmark}}Edit bookmark {{.Bookmark.Number}}{{else}}Add bookmark{{end}}</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="{{if .Bookmark}}/bookmarks/{{.Bookmark.Number}}/edit{{else}}/bookmarks/new{{end}}">
+<label>Title <input type="text" name="name" value="{{.Name}}"></label>
+<label>URL <input type="text" name="url" value="{{.Url}}" required></label>
+<label>Description <textarea name="description" rows="5">{{.Desc}}</textarea></label>
+<label>Tags (comma separated) <input type="text" name="tags" value="{{.Tags}}"></label>
+<p><button type="submit">Save</button> <a href="/">Cancel</a></p>
+</form>
+{{end}}`
+
+const saveTemplateString = `{{define "content"}}
+{{if .Saved}}
+<h1>Saved bookmark {{.Saved.Number}}</h1>
+<p><a href="{{.Saved.Url.String}}">{{if .Saved.Name}}{{.Saved.Name}}{{else}}{{.Saved.Url.String}}{{end}}</a></p>
+<p><a href="/bookmarks/{{.Saved.Number}}/edit">edit</a> <button onclick="window.close()">Close</button></p>
+{{else}}
+<h1>Save page</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/save">
+<label>Title <input type="text" name="title" value="{{.Page.Title}}"></label>
+<label>URL <input type="text" name="url" value="{{.Page.Url}}" required></label>
+<label>Description <textarea name="description" rows="5">{{.Page.Description}}</textarea></label>
+<label>Tags (comma separated) <input type="text" name="tags" value="{{.Tags}}" autofocus></label>
+<p><button type="submit">Save</button></p>
+</form>
+{{end}}
+{{end}}`
+
+const bookmarkletTemplateString = `{{define "content"}}
+<h1>Bookmarklet</h1>
+<p>Drag this link to the bookmarks bar of your browser, then click it on any page to save that page, with any selected text as its description.
+The browser stays logged in to save pages until the token is changed.</p>
+<p><a href="{{.Bookmarklet}}">Save to voile</a></p>
+<p>Extensions and scripts can instead post the url, title, description and tags of a page as JSON or a form to
+<code>/api/v1/save</code>, with the API token in an <code>Authorization: Bearer</code> header.</p>
+{{end}}`
+
+const loginTemplateString = `{{define "content"}}
+<h1>Log in</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/login">
+<input type="hidden" name="next" value="{{.Next}}">
+<label>Token <input type="password" name="token" required autofocus></label>
+<p><button type="submit">Log in</button></p>
+</form>
+{{end}}`
+
+var (
+	indexTemplate = newPageTemplate(indexTemplateString)
+	tagsTemplate  = newPageTemplate(tagsTemplateString)
+	editTemplate  = newPageTemplate(editTemplateString)
+
+	saveTemplate        = newPageTemplate(saveTemplateString)
+	bookmarkletTemplate = newPageTemplate(bookmarkletTemplateString)
+
+	loginTemplate = newPageTemplate(loginTemplateString)
+)
+
+func newPageTemplate(content string) *template.Template {
+	return template.Must(template.Must(template.New("layout").Parse(layoutTemplateString)).Parse(content))
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/DanNixon/voile/db"
+)
+
+type indexPage struct {
+	Query     string
+	Tags      []string
+	Bookmarks []*db.Bookmark
+	Error     string
+}
+
+type tagsPage struct {
+	Tags []tagNode
+}
+
+type tagNode struct {
+	Tag      string
+	Name     string
+	Count    int
+	Children []tagNode
+}
+
+type editPage struct {
+	// Nil when adding a bookmark
+	Bookmark *db.Bookmark
+	Name     string
+	Url      string
+	Desc     string
+	Tags     string
+	Error    string
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	bmks, err := s.read()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page := indexPage{
+		Query: r.URL.Query().Get("q"),
+		Tags:  r.URL.Query()["tag"],
+	}
+
+	status := http.StatusOK
+	page.Bookmarks, err = s.Filter(r.URL.Query(), &bmks)
+	if err != nil {
+		page.Error = err.Error()
+		status = http.StatusBadRequest
+	}
+
+	render(w, status, indexTemplate, page)
+}
+
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	bmks, err := s.read()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tags := bmks.GetAllTags()
+	render(w, http.StatusOK, tagsTemplate, tagsPage{Tags: tagNodes(tags.Tree(), &tags)})
+}
+
+// Routes /bookmarks/new, /bookmarks/N/edit and /bookmarks/N/delete
+func (s *Server) handleBookmark(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/bookmarks/new" {
+		s.handleNew(w, r)
+		return
+	}
+
+	number, action, err := parseBookmarkPath(r.URL.Path, "/bookmarks/")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch action {
+	case "edit":
+		s.handleEdit(w, r, number)
+	case "delete":
+		s.handleDelete(w, r, number)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleNew(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		render(w, http.StatusOK, editTemplate, editPage{})
+		return
+	}
+
+	err := s.update(func(bmks *db.BookmarkLibrary) error {
+		var bm db.Bookmark
+		if err := updateFromForm(&bm, r); err != nil {
+			return err
+		}
+		if existing, err := bmks.GetByUrl(bm.Url.String()); err == nil {
+			return alreadyBookmarkedError(existing)
+		}
+		if err := s.prepare(&bm); err != nil {
+			return err
+		}
+
+		entry := bmks.NewEntry()
+		entry.Name = bm.Name
+		entry.Url = bm.Url
+		entry.Description = bm.Description
+		entry.Tags = bm.Tags
+		return nil
+	})
+	if err != nil {
+		render(w, errorStatus(err, http.StatusBadRequest), editTemplate, formPage(nil, r, err))
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (s *Server) handleEdit(w http.ResponseWriter, r *http.Request, number int) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		bmks, err := s.read()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		bm, err := bmks.GetByNumber(number)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		render(w, http.StatusOK, editTemplate, editPage{
+			Bookmark: bm,
+			Name:     bm.Name,
+			Url:      bm.Url.String(),
+			Desc:     bm.Description,
+			Tags:     strings.Join(bm.Tags.Tags, ", "),
+		})
+		return
+	}
+
+	var edited db.Bookmark
+	err := s.update(func(bmks *db.BookmarkLibrary) error {
+		bm, err := bmks.GetByNumber(number)
+		if err != nil {
+			return err
+		}
+
+		if existing, err := bmks.GetByUrl(strings.TrimSpace(r.PostFormValue("url"))); err == nil && existing.Number != number {
+			return alreadyBookmarkedError(existing)
+		}
+
+		if err = updateFromForm(bm, r); err != nil {
+			return err
+		}
+		if err = s.prepare(bm); err != nil {
+			return err
+		}
+
+		edited = *bm
+		return nil
+	})
+	if err != nil {
+		edited.Number = number
+		render(w, errorStatus(err, http.StatusBadRequest), editTemplate, formPage(&edited, r, err))
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request, number int) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	err := s.update(func(bmks *db.BookmarkLibrary) error {
+		return bmks.DeleteByNumber(number)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Redisplays a submitted form with an error
+func formPage(bm *db.Bookmark, r *http.Request, err error) editPage {
+	return editPage{
+		Bookmark: bm,
+		Name:     r.PostFormValue("name"),
+		Url:      r.PostFormValue("url"),
+		Desc:     r.PostFormValue("description"),
+		Tags:     r.PostFormValue("tags"),
+		Error:    err.Error(),
+	}
+}
+
+func alreadyBookmarkedError(existing *db.Bookmark) error {
+	return errors.New(fmt.Sprintf("%s is already bookmarked as %d", existing.Url.String(), existing.Number))
+}
+
+func tagNodes(nodes []db.TagTreeNode, tags *db.AllTags) []tagNode {
+	var retVal []tagNode
+	for _, n := range nodes {
+		retVal = append(retVal, tagNode{
+			Tag:      n.Tag,
+			Name:     n.Name(),
+			Count:    tags.Total[n.Tag],
+			Children: tagNodes(n.Children, tags),
+		})
+	}
+	return retVal
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m || (r.Method == http.MethodHead && m == http.MethodGet) {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func render(w http.ResponseWriter, status int, t *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	t.ExecuteTemplate(w, "layout", data)
+}
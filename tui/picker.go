@@ -0,0 +1,262 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const pickerPreviewHeight = 8
+
+type PickerItem struct {
+	Title   string
+	Preview []string
+}
+
+type PickerAction struct {
+	Key  tcell.Key
+	Name string
+	Desc string
+}
+
+type PickerResult struct {
+	// Name of the chosen action, empty if the picker was cancelled
+	Action string
+
+	// Filter query when the picker was closed
+	Query string
+
+	// Indices of the chosen items in the original item list
+	Selected []int
+}
+
+// Returns the indices of items matching the query in the order they should
+// be listed
+type PickerFilter func(query string) []int
+
+type picker struct {
+	screen  tcell.Screen
+	items   []PickerItem
+	actions []PickerAction
+	filter  PickerFilter
+
+	query    []rune
+	matches  []int
+	cursor   int
+	offset   int
+	selected map[int]bool
+}
+
+func Pick(items []PickerItem, actions []PickerAction, filter PickerFilter, query string) (PickerResult, error) {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return PickerResult{}, err
+	}
+	if err = screen.Init(); err != nil {
+		return PickerResult{}, err
+	}
+	defer screen.Fini()
+
+	p := picker{
+		screen:   screen,
+		items:    items,
+		actions:  actions,
+		filter:   filter,
+		query:    []rune(query),
+		selected: make(map[int]bool),
+	}
+	p.refilter()
+
+	return p.run(), nil
+}
+
+func (p *picker) run() PickerResult {
+	for {
+		p.draw()
+
+		switch ev := p.screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			p.screen.Sync()
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape, tcell.KeyCtrlC:
+				return PickerResult{Query: string(p.query)}
+			case tcell.KeyUp, tcell.KeyCtrlP:
+				p.move(-1)
+			case tcell.KeyDown, tcell.KeyCtrlN:
+				p.move(1)
+			case tcell.KeyPgUp:
+				p.move(-p.listHeight())
+			case tcell.KeyPgDn:
+				p.move(p.listHeight())
+			case tcell.KeyTab:
+				p.toggleSelected()
+				p.move(1)
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				if len(p.query) > 0 {
+					p.query = p.query[:len(p.query)-1]
+					p.refilter()
+				}
+			case tcell.KeyCtrlU:
+				p.query = nil
+				p.refilter()
+			case tcell.KeyRune:
+				p.query = append(p.query, ev.Rune())
+				p.refilter()
+			default:
+				for _, a := range p.actions {
+					if ev.Key() == a.Key {
+						if result, ok := p.result(a.Name); ok {
+							return result
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (p *picker) result(action string) (PickerResult, bool) {
+	result := PickerResult{
+		Action: action,
+		Query:  string(p.query),
+	}
+
+	// Explicitly selected items take precedence over the one under the cursor
+	for i := range p.selected {
+		result.Selected = append(result.Selected, i)
+	}
+	sort.Ints(result.Selected)
+
+	if len(result.Selected) == 0 {
+		if len(p.matches) == 0 {
+			return result, false
+		}
+		result.Selected = []int{p.matches[p.cursor]}
+	}
+
+	return result, true
+}
+
+func (p *picker) refilter() {
+	p.matches = p.filter(string(p.query))
+	p.cursor = 0
+	p.offset = 0
+}
+
+func (p *picker) move(delta int) {
+	p.cursor += delta
+	if p.cursor >= len(p.matches) {
+		p.cursor = len(p.matches) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+
+	// Keep cursor visible
+	height := p.listHeight()
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+height {
+		p.offset = p.cursor - height + 1
+	}
+}
+
+func (p *picker) toggleSelected() {
+	if len(p.matches) == 0 {
+		return
+	}
+
+	i := p.matches[p.cursor]
+	if p.selected[i] {
+		delete(p.selected, i)
+	} else {
+		p.selected[i] = true
+	}
+}
+
+func (p *picker) listHeight() int {
+	// Prompt, separator, preview and help lines
+	_, height := p.screen.Size()
+	height -= pickerPreviewHeight + 3
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+func (p *picker) draw() {
+	p.screen.Clear()
+	width, height := p.screen.Size()
+
+	normal := tcell.StyleDefault
+	dim := normal.Foreground(tcell.ColorGray)
+	highlight := normal.Reverse(true)
+	preview := normal.Foreground(tcell.ColorGreen)
+
+	// Prompt with match count
+	x := drawText(p.screen, 0, 0, width, normal.Bold(true), "> ")
+	x = drawText(p.screen, x, 0, width, normal, string(p.query))
+	p.screen.ShowCursor(x, 0)
+	drawText(p.screen, x+1, 0, width, dim,
+		fmt.Sprintf("%d/%d (%d selected)", len(p.matches), len(p.items), len(p.selected)))
+
+	// Matching items
+	listHeight := p.listHeight()
+	for row := 0; row < listHeight && p.offset+row < len(p.matches); row++ {
+		idx := p.matches[p.offset+row]
+
+		style := normal
+		if p.offset+row == p.cursor {
+			style = highlight
+		}
+
+		mark := "  "
+		if p.selected[idx] {
+			mark = "* "
+			style = style.Foreground(tcell.ColorGreen)
+		}
+
+		line := mark + p.items[idx].Title
+		if p.offset+row == p.cursor {
+			line += strings.Repeat(" ", width)
+		}
+		drawText(p.screen, 0, row+1, width, style, line)
+	}
+
+	// Preview of item under the cursor
+	previewTop := listHeight + 1
+	drawText(p.screen, 0, previewTop, width, dim, strings.Repeat("─", width))
+	if len(p.matches) > 0 {
+		for row, line := range p.items[p.matches[p.cursor]].Preview {
+			if row >= pickerPreviewHeight {
+				break
+			}
+			drawText(p.screen, 0, previewTop+1+row, width, preview, line)
+		}
+	}
+
+	// Key bindings
+	help := "tab select  esc quit"
+	for _, a := range p.actions {
+		help += fmt.Sprintf("  %s %s", tcell.KeyNames[a.Key], a.Desc)
+	}
+	drawText(p.screen, 0, height-1, width, dim, help)
+
+	p.screen.Show()
+}
+
+// Draws text on a single line, returning the column after the last character
+func drawText(screen tcell.Screen, x, y, maxX int, style tcell.Style, text string) int {
+	for _, r := range text {
+		if x >= maxX {
+			break
+		}
+		screen.SetContent(x, y, r, nil, style)
+		x += runewidth.RuneWidth(r)
+	}
+	return x
+}
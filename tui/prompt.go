@@ -0,0 +1,22 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func Prompt(prompt string) (string, error) {
+	// Prompt
+	fmt.Print(prompt, ": ")
+
+	// Read first line from console
+	reader := bufio.NewReader(os.Stdin)
+	line, _, err := reader.ReadLine()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(line)), nil
+}
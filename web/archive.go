@@ -0,0 +1,238 @@
+package web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var cssUrlRegexp = regexp.MustCompile(`url\(\s*['"]?([^'")]+?)['"]?\s*\)`)
+
+type pageArchiver struct {
+	assets map[string]string
+}
+
+// Fetches a page and returns a self contained copy of it, with stylesheets
+// and images inlined as data URIs and scripts removed
+func ArchivePage(pageUrl url.URL) ([]byte, error) {
+	doc, base, err := DefaultFetcher.GetHTML(pageUrl.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative links are resolved against the page after any redirects
+	if href, ok := findBaseHref(doc); ok {
+		if u, err := base.Parse(href); err == nil {
+			base = u
+		}
+	}
+
+	a := pageArchiver{
+		assets: make(map[string]string),
+	}
+	a.inline(doc, base)
+
+	var buf bytes.Buffer
+	// No timestamp, so an unchanged page archives to identical bytes
+	fmt.Fprintf(&buf, "<!-- Archived from %s -->\n", pageUrl.String())
+	err = html.Render(&buf, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func findBaseHref(n *html.Node) (string, bool) {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Base {
+		for _, attr := range n.Attr {
+			if attr.Key == "href" {
+				return attr.Val, true
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href, ok := findBaseHref(c); ok {
+			return href, true
+		}
+	}
+
+	return "", false
+}
+
+func (a *pageArchiver) inline(n *html.Node, base *url.URL) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+
+		if c.Type == html.ElementNode {
+			switch c.DataAtom {
+			case atom.Script, atom.Base:
+				// Scripts could fetch content that is no longer available
+				n.RemoveChild(c)
+			case atom.Link:
+				a.inlineLink(n, c, base)
+			case atom.Style:
+				if c.FirstChild != nil && c.FirstChild.Type == html.TextNode {
+					c.FirstChild.Data = a.inlineCss(c.FirstChild.Data, base)
+				}
+			default:
+				a.inlineAttributes(c, base)
+				a.inline(c, base)
+			}
+		}
+
+		c = next
+	}
+}
+
+func (a *pageArchiver) inlineLink(parent, n *html.Node, base *url.URL) {
+	rel := strings.ToLower(getAttr(n, "rel"))
+	href := getAttr(n, "href")
+
+	switch {
+	case strings.Contains(rel, "stylesheet"):
+		u, err := base.Parse(href)
+		if err != nil {
+			return
+		}
+
+		css, _, err := fetchAsset(u.String())
+		if err != nil {
+			setAttr(n, "href", u.String())
+			return
+		}
+
+		// Replace the link with the stylesheet itself
+		style := &html.Node{
+			Type:     html.ElementNode,
+			Data:     "style",
+			DataAtom: atom.Style,
+		}
+		if media := getAttr(n, "media"); len(media) > 0 {
+			setAttr(style, "media", media)
+		}
+		style.AppendChild(&html.Node{
+			Type: html.TextNode,
+			Data: a.inlineCss(string(css), u),
+		})
+		parent.InsertBefore(style, n)
+		parent.RemoveChild(n)
+	case strings.Contains(rel, "icon"):
+		setAttr(n, "href", a.dataUri(href, base))
+	default:
+		if len(href) > 0 {
+			setAttr(n, "href", resolve(href, base))
+		}
+	}
+}
+
+func (a *pageArchiver) inlineAttributes(n *html.Node, base *url.URL) {
+	for i, attr := range n.Attr {
+		switch attr.Key {
+		case "src", "poster":
+			if n.DataAtom == atom.Img || n.DataAtom == atom.Video || n.DataAtom == atom.Source || n.DataAtom == atom.Input {
+				n.Attr[i].Val = a.dataUri(attr.Val, base)
+			} else {
+				n.Attr[i].Val = resolve(attr.Val, base)
+			}
+		case "href", "action":
+			// Links still go to the live site
+			n.Attr[i].Val = resolve(attr.Val, base)
+		case "style":
+			n.Attr[i].Val = a.inlineCss(attr.Val, base)
+		}
+	}
+
+	// Alternative image sources would not be available offline
+	removeAttr(n, "srcset")
+}
+
+func (a *pageArchiver) inlineCss(css string, base *url.URL) string {
+	return cssUrlRegexp.ReplaceAllStringFunc(css, func(match string) string {
+		ref := cssUrlRegexp.FindStringSubmatch(match)[1]
+		return fmt.Sprintf("url(\"%s\")", a.dataUri(ref, base))
+	})
+}
+
+// Returns the asset as a data URI, or its absolute URL if it cannot be fetched
+// (including if it is too large)
+func (a *pageArchiver) dataUri(ref string, base *url.URL) string {
+	if strings.HasPrefix(ref, "data:") {
+		return ref
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	if uri, ok := a.assets[u.String()]; ok {
+		return uri
+	}
+
+	data, contentType, err := fetchAsset(u.String())
+	if err != nil {
+		return u.String()
+	}
+
+	uri := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	a.assets[u.String()] = uri
+	return uri
+}
+
+func fetchAsset(assetUrl string) ([]byte, string, error) {
+	resp, err := DefaultFetcher.Get(assetUrl)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.Body, resp.ContentType, nil
+}
+
+func resolve(ref string, base *url.URL) string {
+	// Links within the page still work offline
+	if strings.HasPrefix(ref, "#") {
+		return ref
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, attr := range n.Attr {
+		if attr.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func removeAttr(n *html.Node, key string) {
+	for i, attr := range n.Attr {
+		if attr.Key == key {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package web_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/web"
+)
+
+const testArchivePage = `<html><head>
+<base href="/sub/">
+<link rel="stylesheet" href="style.css" media="screen">
+<link rel="icon" href="/icon.png">
+<link rel="alternate" href="feed.xml">
+<script src="/app.js"></script>
+<style>h1 { background: url('../bg.png'); }</style>
+</head><body>
+<h1 style="background: url(/bg.png)">Title</h1>
+<img src="img.png" srcset="big.png 2x">
+<img src="/missing.png">
+<a href="other.html">Other</a> <a href="#top">Top</a>
+<script>alert(1)</script>
+</body></html>`
+
+func createTestArchiveServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testArchivePage))
+	})
+	mux.HandleFunc("/sub/style.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`body { background: url("../bg.png"); }`))
+	})
+	for _, p := range []string{"/icon.png", "/bg.png", "/sub/img.png"} {
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png"))
+		})
+	}
+	return httptest.NewServer(mux)
+}
+
+func archiveTestPage(t *testing.T, s *httptest.Server) string {
+	u, _ := url.Parse(s.URL + "/page")
+	data, err := web.ArchivePage(*u)
+	assert.Nil(t, err)
+	return string(data)
+}
+
+func TestArchivePage(t *testing.T) {
+	s := createTestArchiveServer()
+	defer s.Close()
+
+	page := archiveTestPage(t, s)
+	png := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("png"))
+
+	assert.True(t, strings.HasPrefix(page, "<!-- Archived from "+s.URL+"/page -->\n"))
+
+	// Scripts and the base element are removed
+	assert.NotContains(t, page, "<script")
+	assert.NotContains(t, page, "<base")
+
+	// Stylesheets are inlined with their URLs resolved against the stylesheet
+	assert.NotContains(t, page, "style.css")
+	assert.Contains(t, page, `<style media="screen">body { background: url("`+png+`"); }</style>`)
+	assert.Contains(t, page, `h1 { background: url("`+png+`"); }`)
+	assert.Contains(t, page, `style="background: url(&#34;`+png+`&#34;)"`)
+
+	// Images and icons are inlined, relative to the base href
+	assert.Contains(t, page, `<link rel="icon" href="`+png+`"/>`)
+	assert.Contains(t, page, `<img src="`+png+`"/>`)
+	assert.NotContains(t, page, "srcset")
+
+	// Anything that can not be fetched is left as an absolute URL
+	assert.Contains(t, page, `<img src="`+s.URL+`/missing.png"/>`)
+
+	// Links go to the live site, except those within the page
+	assert.Contains(t, page, `<link rel="alternate" href="`+s.URL+`/sub/feed.xml"/>`)
+	assert.Contains(t, page, `<a href="`+s.URL+`/sub/other.html">`)
+	assert.Contains(t, page, `<a href="#top">`)
+}
+
+func TestArchivePageUnchanged(t *testing.T) {
+	s := createTestArchiveServer()
+	defer s.Close()
+
+	// Identical pages must share an address in the archive
+	assert.Equal(t, archiveTestPage(t, s), archiveTestPage(t, s))
+}
+
+func TestArchivePageNotFound(t *testing.T) {
+	s := createTestArchiveServer()
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL + "/nope")
+	_, err := web.ArchivePage(*u)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,166 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const maxRedirects = 10
+
+type LinkStatus struct {
+	Url        string
+	StatusCode int
+	Err        error
+
+	// URL of the last response, equal to Url if not redirected
+	FinalUrl string
+
+	// Set if every redirect followed was permanent
+	Permanent bool
+}
+
+func (s *LinkStatus) IsRedirected() bool {
+	return s.FinalUrl != s.Url
+}
+
+type LinkChecker struct {
+	Fetcher *Fetcher
+
+	// Number of links checked at the same time
+	Concurrency int
+
+	// Minimum time between requests to the same host
+	HostInterval time.Duration
+
+	client   *http.Client
+	mutex    sync.Mutex
+	hostNext map[string]time.Time
+}
+
+func NewLinkChecker(fetcher *Fetcher, concurrency int, hostInterval time.Duration) *LinkChecker {
+	// Redirects are followed manually to record them
+	client := *fetcher.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &LinkChecker{
+		Fetcher:      fetcher,
+		Concurrency:  concurrency,
+		HostInterval: hostInterval,
+		client:       &client,
+	}
+}
+
+// Checks each URL, calling done (from any goroutine) as each finishes
+func (c *LinkChecker) CheckAll(urls []string, done func(status LinkStatus)) {
+	work := make(chan string)
+
+	workers := c.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range work {
+				done(c.Check(u))
+			}
+		}()
+	}
+
+	for _, u := range urls {
+		work <- u
+	}
+	close(work)
+
+	wg.Wait()
+}
+
+func (c *LinkChecker) Check(rawUrl string) LinkStatus {
+	status := LinkStatus{
+		Url:       rawUrl,
+		FinalUrl:  rawUrl,
+		Permanent: true,
+	}
+
+	current, err := url.Parse(rawUrl)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+
+	for i := 0; ; i++ {
+		if i > maxRedirects {
+			status.Err = errors.New("Too many redirects")
+			return status
+		}
+
+		c.waitForHost(current.Host)
+
+		req, err := c.Fetcher.NewRequest("GET", current.String())
+		if err != nil {
+			status.Err = err
+			return status
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			status.Err = err
+			return status
+		}
+		resp.Body.Close()
+
+		status.StatusCode = resp.StatusCode
+		status.FinalUrl = current.String()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || len(location) == 0 {
+			break
+		}
+
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		default:
+			status.Permanent = false
+		}
+
+		// Location may be relative to the current URL
+		next, err := current.Parse(location)
+		if err != nil {
+			status.Err = err
+			return status
+		}
+		current = next
+	}
+
+	if !status.IsRedirected() {
+		status.Permanent = false
+	}
+
+	return status
+}
+
+// Blocks until a request may be made to the host
+func (c *LinkChecker) waitForHost(host string) {
+	c.mutex.Lock()
+	if c.hostNext == nil {
+		c.hostNext = make(map[string]time.Time)
+	}
+
+	now := time.Now()
+	next := c.hostNext[host]
+	if next.Before(now) {
+		next = now
+	}
+	c.hostNext[host] = next.Add(c.HostInterval)
+	c.mutex.Unlock()
+
+	time.Sleep(next.Sub(now))
+}
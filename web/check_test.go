@@ -0,0 +1,147 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/web"
+)
+
+func createTestLinkServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved-again", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved-again", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ok", http.StatusPermanentRedirect)
+	})
+	mux.HandleFunc("/found", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/moved-then-found", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/found", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestLinkChecker(concurrency int, hostInterval time.Duration) *web.LinkChecker {
+	f := web.NewFetcher()
+	f.Client.Timeout = 200 * time.Millisecond
+	return web.NewLinkChecker(f, concurrency, hostInterval)
+}
+
+func TestLinkCheckerOk(t *testing.T) {
+	s := createTestLinkServer()
+	defer s.Close()
+
+	status := newTestLinkChecker(1, 0).Check(s.URL + "/ok")
+	assert.Nil(t, status.Err)
+	assert.Equal(t, http.StatusOK, status.StatusCode)
+	assert.False(t, status.IsRedirected())
+	assert.False(t, status.Permanent)
+}
+
+func TestLinkCheckerPermanentRedirect(t *testing.T) {
+	s := createTestLinkServer()
+	defer s.Close()
+
+	// Both 301 and 308 are permanent, relative locations are followed
+	status := newTestLinkChecker(1, 0).Check(s.URL + "/moved")
+	assert.Nil(t, status.Err)
+	assert.Equal(t, http.StatusOK, status.StatusCode)
+	assert.True(t, status.IsRedirected())
+	assert.Equal(t, s.URL+"/ok", status.FinalUrl)
+	assert.True(t, status.Permanent)
+}
+
+func TestLinkCheckerTemporaryRedirect(t *testing.T) {
+	s := createTestLinkServer()
+	defer s.Close()
+
+	c := newTestLinkChecker(1, 0)
+
+	status := c.Check(s.URL + "/found")
+	assert.Equal(t, http.StatusOK, status.StatusCode)
+	assert.Equal(t, s.URL+"/ok", status.FinalUrl)
+	assert.False(t, status.Permanent)
+
+	// Any temporary redirect along the way means the move is not permanent
+	status = c.Check(s.URL + "/moved-then-found")
+	assert.Equal(t, s.URL+"/ok", status.FinalUrl)
+	assert.False(t, status.Permanent)
+}
+
+func TestLinkCheckerNotFound(t *testing.T) {
+	s := createTestLinkServer()
+	defer s.Close()
+
+	status := newTestLinkChecker(1, 0).Check(s.URL + "/nope")
+	assert.Nil(t, status.Err)
+	assert.Equal(t, http.StatusNotFound, status.StatusCode)
+	assert.False(t, status.IsRedirected())
+}
+
+func TestLinkCheckerTooManyRedirects(t *testing.T) {
+	s := createTestLinkServer()
+	defer s.Close()
+
+	status := newTestLinkChecker(1, 0).Check(s.URL + "/loop")
+	assert.NotNil(t, status.Err)
+}
+
+func TestLinkCheckerTimeout(t *testing.T) {
+	s := createTestLinkServer()
+	defer s.Close()
+
+	status := newTestLinkChecker(1, 0).Check(s.URL + "/slow")
+	assert.NotNil(t, status.Err)
+	assert.Equal(t, 0, status.StatusCode)
+}
+
+func TestLinkCheckerHostInterval(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []time.Time
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, time.Now())
+		mutex.Unlock()
+	}))
+	defer s.Close()
+
+	interval := 100 * time.Millisecond
+	c := newTestLinkChecker(3, interval)
+
+	var checked []string
+	c.CheckAll([]string{s.URL + "/a", s.URL + "/b", s.URL + "/c"}, func(status web.LinkStatus) {
+		mutex.Lock()
+		checked = append(checked, status.Url)
+		mutex.Unlock()
+	})
+
+	sort.Strings(checked)
+	assert.Equal(t, []string{s.URL + "/a", s.URL + "/b", s.URL + "/c"}, checked)
+
+	// Requests to the same host are spaced out even when checked concurrently
+	assert.Equal(t, 3, len(requests))
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Before(requests[j]) })
+	for i := 1; i < len(requests); i++ {
+		assert.True(t, requests[i].Sub(requests[i-1]) >= interval-10*time.Millisecond,
+			"requests %d and %d only %s apart", i-1, i, requests[i].Sub(requests[i-1]))
+	}
+}
@@ -0,0 +1,191 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	DefaultUserAgent = "voile (+https://github.com/DanNixon/voile)"
+	DefaultTimeout   = 30 * time.Second
+	DefaultMaxSize   = 20 * 1024 * 1024
+)
+
+// Used by all network features, replace to change how requests are made
+var DefaultFetcher = NewFetcher()
+
+type StatusError struct {
+	Url    string
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Fetching %s failed: %s", e.Url, e.Status)
+}
+
+type ContentTypeError struct {
+	Url         string
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("Fetching %s failed: unexpected content type %s", e.Url, e.ContentType)
+}
+
+type TooLargeError struct {
+	Url   string
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("Fetching %s failed: response larger than %d bytes", e.Url, e.Limit)
+}
+
+type Fetcher struct {
+	Client    *http.Client
+	UserAgent string
+
+	// Largest response body that will be read
+	MaxSize int64
+}
+
+type Response struct {
+	// URL after any redirects
+	Url *url.URL
+
+	Header http.Header
+	Body   []byte
+
+	// Media type without parameters, e.g. text/html
+	ContentType string
+}
+
+// Fetcher with default settings, using any proxy set in the environment
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Client: &http.Client{
+			Timeout: DefaultTimeout,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+		},
+		UserAgent: DefaultUserAgent,
+		MaxSize:   DefaultMaxSize,
+	}
+}
+
+// Sends all requests via a proxy rather than the one from the environment
+func (f *Fetcher) SetProxy(proxy string) error {
+	proxyUrl, err := url.Parse(proxy)
+	if err != nil {
+		return err
+	}
+
+	f.Client.Transport = &http.Transport{
+		Proxy: http.ProxyURL(proxyUrl),
+	}
+	return nil
+}
+
+// Copy of the fetcher with a different timeout
+func (f *Fetcher) WithTimeout(timeout time.Duration) *Fetcher {
+	client := *f.Client
+	client.Timeout = timeout
+
+	copy := *f
+	copy.Client = &client
+	return &copy
+}
+
+func (f *Fetcher) NewRequest(method, rawUrl string) (*http.Request, error) {
+	req, err := http.NewRequest(method, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", f.UserAgent)
+	return req, nil
+}
+
+// Fetches a URL, failing on error statuses and oversized responses
+func (f *Fetcher) Get(rawUrl string) (*Response, error) {
+	req, err := f.NewRequest("GET", rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &StatusError{rawUrl, resp.Status}
+	}
+
+	if f.MaxSize > 0 && resp.ContentLength > f.MaxSize {
+		return nil, &TooLargeError{rawUrl, f.MaxSize}
+	}
+
+	// Read one byte more than allowed to detect oversized bodies
+	body := io.Reader(resp.Body)
+	if f.MaxSize > 0 {
+		body = io.LimitReader(resp.Body, f.MaxSize+1)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if f.MaxSize > 0 && int64(len(data)) > f.MaxSize {
+		return nil, &TooLargeError{rawUrl, f.MaxSize}
+	}
+
+	// Fall back to sniffing when the server does not say
+	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+
+	return &Response{
+		Url:         resp.Request.URL,
+		Header:      resp.Header,
+		Body:        data,
+		ContentType: strings.ToLower(contentType),
+	}, nil
+}
+
+// Fetches and parses an HTML page, decoding it from the character set given
+// in the headers or the page itself
+func (f *Fetcher) GetHTML(rawUrl string) (*html.Node, *url.URL, error) {
+	resp, err := f.Get(rawUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.ContentType != "text/html" && resp.ContentType != "application/xhtml+xml" {
+		return nil, nil, &ContentTypeError{rawUrl, resp.ContentType}
+	}
+
+	r, err := charset.NewReader(bytes.NewReader(resp.Body), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return doc, resp.Url, nil
+}
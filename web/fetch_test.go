@@ -0,0 +1,106 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DanNixon/voile/web"
+)
+
+func createTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte("<html><head><title>Caf\xe9</title></head></html>"))
+	})
+	mux.HandleFunc("/meta-charset", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><meta charset=\"windows-1252\"><title>\x93Quoted\x94</title></head></html>"))
+	})
+	mux.HandleFunc("/user-agent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<title>" + r.UserAgent() + "</title>"))
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 2048))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFetcherGetHTMLHeaderCharset(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	doc, _, err := web.NewFetcher().GetHTML(s.URL + "/latin1")
+	assert.Nil(t, err)
+
+	title, err := web.FindTitleElementInDocument(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, "Café", title)
+}
+
+func TestFetcherGetHTMLMetaCharset(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	doc, _, err := web.NewFetcher().GetHTML(s.URL + "/meta-charset")
+	assert.Nil(t, err)
+
+	title, err := web.FindTitleElementInDocument(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, "“Quoted”", title)
+}
+
+func TestFetcherUserAgent(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	f := web.NewFetcher()
+	f.UserAgent = "test agent"
+
+	doc, _, err := f.GetHTML(s.URL + "/user-agent")
+	assert.Nil(t, err)
+
+	title, _ := web.FindTitleElementInDocument(doc)
+	assert.Equal(t, "test agent", title)
+}
+
+func TestFetcherStatusError(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	_, err := web.NewFetcher().Get(s.URL + "/missing")
+	assert.IsType(t, &web.StatusError{}, err)
+}
+
+func TestFetcherContentTypeError(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	_, _, err := web.NewFetcher().GetHTML(s.URL + "/json")
+	assert.IsType(t, &web.ContentTypeError{}, err)
+}
+
+func TestFetcherTooLarge(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	f := web.NewFetcher()
+	f.MaxSize = 1024
+
+	_, err := f.Get(s.URL + "/large")
+	assert.IsType(t, &web.TooLargeError{}, err)
+
+	f.MaxSize = 4096
+
+	resp, err := f.Get(s.URL + "/large")
+	assert.Nil(t, err)
+	assert.Equal(t, 2048, len(resp.Body))
+}
@@ -2,7 +2,6 @@ package web
 
 import (
 	"errors"
-	"net/http"
 	"net/url"
 	"strings"
 
@@ -27,12 +26,7 @@ func FindTitleElementInDocument(n *html.Node) (string, error) {
 }
 
 func FindTitleElement(url url.URL) (string, error) {
-	resp, err := http.Get(url.String())
-	if err != nil {
-		return "", err
-	}
-
-	doc, err := html.Parse(resp.Body)
+	doc, _, err := DefaultFetcher.GetHTML(url.String())
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Formats tried in order when parsing a published date
+var publishedDateFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+type PageMetadata struct {
+	Title string
+	// Text of the title element, which Title falls back to
+	PageTitle    string
+	Description  string
+	CanonicalUrl string
+	Author       string
+	Published    time.Time
+	Favicon      string
+}
+
+func FetchMetadata(pageUrl url.URL) (PageMetadata, error) {
+	doc, finalUrl, err := DefaultFetcher.GetHTML(pageUrl.String())
+	if err != nil {
+		return PageMetadata{}, err
+	}
+
+	return ExtractMetadata(doc, finalUrl), nil
+}
+
+// Extracts metadata from a page, preferring OpenGraph properties over plain
+// HTML equivalents. URLs are resolved against base.
+func ExtractMetadata(doc *html.Node, base *url.URL) PageMetadata {
+	meta := make(map[string]string)
+	links := make(map[string]string)
+	collectMetadata(doc, meta, links)
+
+	var md PageMetadata
+
+	md.PageTitle, _ = FindTitleElementInDocument(doc)
+	md.PageTitle = strings.TrimSpace(md.PageTitle)
+
+	md.Title = strings.TrimSpace(firstNonEmpty(meta["og:title"], meta["twitter:title"]))
+	if len(md.Title) == 0 {
+		md.Title = md.PageTitle
+	}
+
+	md.Description = firstNonEmpty(meta["og:description"], meta["description"], meta["twitter:description"])
+
+	if canonical := firstNonEmpty(links["canonical"], meta["og:url"]); len(canonical) > 0 {
+		md.CanonicalUrl = resolveAbsolute(canonical, base)
+	}
+
+	md.Author = firstNonEmpty(meta["author"], meta["article:author"], meta["twitter:creator"])
+
+	published := firstNonEmpty(meta["article:published_time"], meta["datepublished"], meta["date"], meta["dc.date"])
+	for _, f := range publishedDateFormats {
+		if t, err := time.Parse(f, published); err == nil {
+			md.Published = t
+			break
+		}
+	}
+
+	// Only icons the page declares, so nothing is recorded for pages without
+	// any metadata
+	if icon := firstNonEmpty(links["icon"], links["shortcut icon"], links["apple-touch-icon"]); len(icon) > 0 {
+		md.Favicon = resolveAbsolute(icon, base)
+	}
+
+	return md
+}
+
+// Collects meta element content by name or property and link element targets
+// by rel, keeping the first of each
+func collectMetadata(n *html.Node, meta, links map[string]string) {
+	if n.Type == html.ElementNode {
+		switch n.DataAtom {
+		case atom.Meta:
+			key := strings.ToLower(firstNonEmpty(getAttr(n, "property"), getAttr(n, "name"), getAttr(n, "itemprop")))
+			content := strings.TrimSpace(getAttr(n, "content"))
+			if _, ok := meta[key]; !ok && len(key) > 0 && len(content) > 0 {
+				meta[key] = content
+			}
+		case atom.Link:
+			rel := strings.ToLower(strings.TrimSpace(getAttr(n, "rel")))
+			href := strings.TrimSpace(getAttr(n, "href"))
+			if _, ok := links[rel]; !ok && len(rel) > 0 && len(href) > 0 {
+				links[rel] = href
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectMetadata(c, meta, links)
+	}
+}
+
+func resolveAbsolute(ref string, base *url.URL) string {
+	if base == nil {
+		return ref
+	}
+	return resolve(ref, base)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+	return ""
+}
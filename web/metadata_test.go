@@ -0,0 +1,66 @@
+package web_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+
+	"github.com/DanNixon/voile/web"
+)
+
+func extractTestMetadata(t *testing.T, page string) web.PageMetadata {
+	doc, err := html.Parse(strings.NewReader(page))
+	assert.Nil(t, err)
+
+	base, _ := url.Parse("https://example.com/blog/post?utm_source=feed")
+	return web.ExtractMetadata(doc, base)
+}
+
+func TestExtractMetadataOpenGraph(t *testing.T) {
+	md := extractTestMetadata(t, `<html><head>
+<title>Post | Example Blog</title>
+<meta property="og:title" content="Post">
+<meta property="og:description" content="All about the post">
+<meta name="description" content="Plain description">
+<meta name="author" content="Someone">
+<meta property="article:published_time" content="2019-05-06T07:08:09Z">
+<link rel="canonical" href="/blog/post">
+<link rel="icon" href="/static/icon.png">
+</head></html>`)
+
+	assert.Equal(t, "Post", md.Title)
+	assert.Equal(t, "Post | Example Blog", md.PageTitle)
+	assert.Equal(t, "All about the post", md.Description)
+	assert.Equal(t, "https://example.com/blog/post", md.CanonicalUrl)
+	assert.Equal(t, "Someone", md.Author)
+	assert.Equal(t, time.Date(2019, time.May, 6, 7, 8, 9, 0, time.UTC), md.Published)
+	assert.Equal(t, "https://example.com/static/icon.png", md.Favicon)
+}
+
+func TestExtractMetadataPlain(t *testing.T) {
+	md := extractTestMetadata(t, `<html><head>
+<title> Plain page </title>
+<meta name="description" content="Plain description">
+<meta name="date" content="2019-05-06">
+</head></html>`)
+
+	assert.Equal(t, "Plain page", md.Title)
+	assert.Equal(t, "Plain page", md.PageTitle)
+	assert.Equal(t, "Plain description", md.Description)
+	assert.Equal(t, "", md.CanonicalUrl)
+	assert.Equal(t, "", md.Author)
+	assert.Equal(t, time.Date(2019, time.May, 6, 0, 0, 0, 0, time.UTC), md.Published)
+	assert.Equal(t, "", md.Favicon)
+}
+
+func TestExtractMetadataEmpty(t *testing.T) {
+	md := extractTestMetadata(t, `<html></html>`)
+
+	assert.Equal(t, "", md.Title)
+	assert.Equal(t, "", md.Description)
+	assert.True(t, md.Published.IsZero())
+}
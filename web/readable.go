@@ -0,0 +1,169 @@
+package web
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Paragraphs shorter than this are unlikely to be part of the main content
+const minReadableParagraphLength = 25
+
+// Elements that never contain readable content
+var unreadableElements = map[atom.Atom]bool{
+	atom.Aside:    true,
+	atom.Button:   true,
+	atom.Footer:   true,
+	atom.Form:     true,
+	atom.Header:   true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Noscript: true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+}
+
+// Elements that start a new line of text
+var blockElements = map[atom.Atom]bool{
+	atom.Article:    true,
+	atom.Blockquote: true,
+	atom.Br:         true,
+	atom.Dd:         true,
+	atom.Div:        true,
+	atom.Dt:         true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Li:         true,
+	atom.Main:       true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Td:         true,
+	atom.Th:         true,
+	atom.Tr:         true,
+}
+
+func FetchText(pageUrl url.URL) (string, error) {
+	doc, _, err := DefaultFetcher.GetHTML(pageUrl.String())
+	if err != nil {
+		return "", err
+	}
+
+	return ExtractText(doc), nil
+}
+
+// Extracts the readable text of the main content of a page, leaving out
+// navigation, headers, footers and the like
+func ExtractText(doc *html.Node) string {
+	root := findMainContent(doc)
+
+	var b strings.Builder
+	writeReadableText(&b, root)
+
+	// Collapse whitespace within lines and drop empty lines
+	var lines []string
+	for _, l := range strings.Split(b.String(), "\n") {
+		if l = strings.Join(strings.Fields(l), " "); len(l) > 0 {
+			lines = append(lines, l)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func findMainContent(doc *html.Node) *html.Node {
+	// Pages marked up with a single main element make this easy
+	for _, match := range []func(*html.Node) bool{
+		func(n *html.Node) bool { return n.DataAtom == atom.Main || getAttr(n, "role") == "main" },
+		func(n *html.Node) bool { return n.DataAtom == atom.Article },
+	} {
+		if found := findElements(doc, match); len(found) == 1 {
+			return found[0]
+		}
+	}
+
+	// Otherwise the element containing the most paragraph text wins
+	scores := make(map[*html.Node]int)
+	for _, p := range findElements(doc, func(n *html.Node) bool { return n.DataAtom == atom.P }) {
+		var b strings.Builder
+		writeReadableText(&b, p)
+		length := len(strings.TrimSpace(b.String()))
+		if length < minReadableParagraphLength || p.Parent == nil {
+			continue
+		}
+
+		scores[p.Parent] += length
+		if p.Parent.Parent != nil {
+			scores[p.Parent.Parent] += length / 2
+		}
+	}
+
+	// Ties go to the first in the document, so the result is the same every time
+	var best *html.Node
+	for _, n := range findElements(doc, func(n *html.Node) bool { return scores[n] > 0 }) {
+		if best == nil || scores[n] > scores[best] {
+			best = n
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	if body := findElements(doc, func(n *html.Node) bool { return n.DataAtom == atom.Body }); len(body) > 0 {
+		return body[0]
+	}
+	return doc
+}
+
+func findElements(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var found []*html.Node
+
+	if n.Type == html.ElementNode {
+		if unreadableElements[n.DataAtom] {
+			return nil
+		}
+		if match(n) {
+			found = append(found, n)
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		found = append(found, findElements(c, match)...)
+	}
+
+	return found
+}
+
+func writeReadableText(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if unreadableElements[n.DataAtom] {
+			return
+		}
+	}
+
+	block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+	if block {
+		b.WriteString("\n")
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeReadableText(b, c)
+	}
+
+	if block {
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,58 @@
+package web_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+
+	"github.com/DanNixon/voile/web"
+)
+
+func extractTestText(t *testing.T, page string) string {
+	doc, err := html.Parse(strings.NewReader(page))
+	assert.Nil(t, err)
+
+	return web.ExtractText(doc)
+}
+
+func TestExtractTextMainElement(t *testing.T) {
+	text := extractTestText(t, `<html><body>
+<nav><a href="/">Home</a></nav>
+<main><h1>Heading</h1><p>First   paragraph.</p><script>var x;</script><p>Second</p></main>
+<footer>Copyright</footer>
+</body></html>`)
+
+	assert.Equal(t, "Heading\nFirst paragraph.\nSecond", text)
+}
+
+func TestExtractTextParagraphs(t *testing.T) {
+	text := extractTestText(t, `<html><body>
+<div class="sidebar"><p>Short</p><p>Links</p></div>
+<div class="content">
+<p>This is a long paragraph of the main content of the page.</p>
+<p>Another long paragraph with more of the main content.</p>
+</div>
+</body></html>`)
+
+	assert.Equal(t, "This is a long paragraph of the main content of the page.\nAnother long paragraph with more of the main content.", text)
+}
+
+func TestExtractTextBody(t *testing.T) {
+	text := extractTestText(t, `<html><head><title>Title</title><style>p {}</style></head><body>Just <b>some</b> text</body></html>`)
+
+	assert.Equal(t, "Just some text", text)
+}
+
+func TestExtractTextParagraphsTie(t *testing.T) {
+	page := `<html><body>
+<div><p>The first paragraph of content has this length!</p></div>
+<div><p>The other paragraph of content has this length!</p></div>
+</body></html>`
+
+	// Equally scored elements are chosen in document order
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "The first paragraph of content has this length!", extractTestText(t, page))
+	}
+}